@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rodaine/table"
+)
+
+// createAlternativeTeams is the best team followed by the next best, up to count of
+// them, where every team has at least minDifference players the others don't.
+func createAlternativeTeams(playerTypes []PlayerType, startingPlayers []StartingPlayer, constraints TeamConstraints, count int, minDifference int) ([]BestTeam, error) {
+	if minDifference < 1 {
+		minDifference = 1
+	}
+	teams := make([]BestTeam, 0, count)
+	for len(teams) < count {
+		team, err := bestLineupDifferentFrom(playerTypes, startingPlayers, constraints, teams, minDifference)
+		if err != nil {
+			if len(teams) > 0 {
+				// there are no more teams different enough
+				break
+			}
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+// teamScore is the total of the given value over the team's players.
+func teamScore(team BestTeam, value func(StartingPlayer) float32) float32 {
+	var score float32
+	for _, player := range team.Players() {
+		score += value(player)
+	}
+	return score
+}
+
+// teamDifference is who's in the team but not the other, and who's in the other but
+// not the team.
+func teamDifference(team BestTeam, other BestTeam) ([]Player, []Player) {
+	in := make(map[PlayerID]bool, 0)
+	for _, player := range team.Players() {
+		in[player.Player.ID] = true
+	}
+	inOther := make(map[PlayerID]bool, 0)
+	for _, player := range other.Players() {
+		inOther[player.Player.ID] = true
+	}
+
+	added := make([]Player, 0)
+	for _, player := range team.Players() {
+		if !inOther[player.Player.ID] {
+			added = append(added, player.Player)
+		}
+	}
+	removed := make([]Player, 0)
+	for _, player := range other.Players() {
+		if !in[player.Player.ID] {
+			removed = append(removed, player.Player)
+		}
+	}
+	return added, removed
+}
+
+func printAlternativeTeams(teams []BestTeam, minDifference int) {
+	if len(teams) == 0 {
+		return
+	}
+	headerFmt, columnFmt := tableFormat()
+	best := teams[0]
+
+	// the alternatives ignore the budget, so they're compared with the best team that does too
+	fmt.Printf("The best team with no budget, which the alternatives are compared with:\n")
+	bestTbl := table.New("Type", "Name", "Form", "PPG", "WPPG", "Score", "Picked", "Rank (Type)", "Cost", "Opponent")
+	bestTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	appendOptions := AppendOptions{withPickedPercentage: true, withRank: true}
+	appendToTable(bestTbl, best.Goalkeepers, appendOptions)
+	appendToTable(bestTbl, best.Defenders, appendOptions)
+	appendToTable(bestTbl, best.Midfielders, appendOptions)
+	appendToTable(bestTbl, best.Forwards, appendOptions)
+	bestTbl.Print()
+	fmt.Println()
+
+	fmt.Printf("Alternative teams, each with at least %d players different:\n", minDifference)
+	// the teams are picked by risk adjusted score, so that's what they're behind by
+	riskAdjusted := scoringConfig.RiskAversion != 0
+	headers := []interface{}{"Team", "Score"}
+	if riskAdjusted {
+		headers = append(headers, "Risk Adjusted")
+	}
+	headers = append(headers, "Behind", "In", "Out")
+	tbl := table.New(headers...)
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	bestScore := teamScore(best, StartingPlayer.RiskAdjustedScore)
+	for i, team := range teams {
+		row := []interface{}{i + 1, fmt.Sprintf("%.0f", teamScore(team, StartingPlayer.Score))}
+		if i == 0 {
+			row[0] = "Best (no budget)"
+		}
+		if riskAdjusted {
+			row = append(row, fmt.Sprintf("%.0f", teamScore(team, StartingPlayer.RiskAdjustedScore)))
+		}
+		if i == 0 {
+			row = append(row, "-", "-", "-")
+		} else {
+			added, removed := teamDifference(team, best)
+			row = append(row,
+				fmt.Sprintf("%.0f", bestScore-teamScore(team, StartingPlayer.RiskAdjustedScore)),
+				playerNames(added),
+				playerNames(removed),
+			)
+		}
+		tbl.AddRow(row...)
+	}
+	tbl.Print()
+	fmt.Println()
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -13,6 +15,9 @@ const (
 	fixturesApi       = "https://fantasy.premierleague.com/api/fixtures/"
 	statsApi          = "https://fantasy.premierleague.com/api/bootstrap-static/"
 	playerFixturesApi = "https://fantasy.premierleague.com/api/element-summary/"
+
+	// number of element-summary requests made at once when loading history
+	historyWorkers = 8
 )
 
 type apiTeam struct {
@@ -48,6 +53,7 @@ type apiElement struct {
 	YellowCards              int     `json:"yellow_cards"`
 	RedCards                 int     `json:"red_cards"`
 	Bonus                    int     `json:"bonus"`
+	Saves                    int     `json:"saves"`
 	Starts                   int     `json:"starts"`
 	StartsPerNinety          float32 `json:"starts_per_90"`
 	ICTIndex                 string  `json:"ict_index"`
@@ -56,6 +62,9 @@ type apiElement struct {
 	ChanceOfPlayingThisRound *int    `json:"chance_of_playing_this_round"`
 	ChanceOfPlayingNextRound *int    `json:"chance_of_playing_next_round"`
 	SelectedByPercent        string  `json:"selected_by_percent"`
+	PenaltiesOrder           *int    `json:"penalties_order"`
+	CornersAndIndirectOrder  *int    `json:"corners_and_indirect_freekicks_order"`
+	DirectFreekicksOrder     *int    `json:"direct_freekicks_order"`
 }
 
 type apiElementType struct {
@@ -84,19 +93,35 @@ type apiPlayerFixturesAndHistory struct {
 // }
 
 type apiPlayerHistory struct {
-	ElementID   int `json:"element"`
-	FixtureID   int `json:"fixture"`
-	Minutes     int `json:"minutes"`
-	TotalPoints int `json:"total_points"`
+	ElementID     int       `json:"element"`
+	FixtureID     int       `json:"fixture"`
+	Round         int       `json:"round"`
+	KickoffTime   time.Time `json:"kickoff_time"`
+	Minutes       int       `json:"minutes"`
+	Starts        int       `json:"starts"`
+	TotalPoints   int       `json:"total_points"`
+	Goals         int       `json:"goals_scored"`
+	Assists       int       `json:"assists"`
+	CleanSheets   int       `json:"clean_sheets"`
+	GoalsConceded int       `json:"goals_conceded"`
+	Saves         int       `json:"saves"`
+	YellowCards   int       `json:"yellow_cards"`
+	RedCards      int       `json:"red_cards"`
+	Bonus         int       `json:"bonus"`
+	BPS           int       `json:"bps"`
+	Value         int       `json:"value"`
 }
 
 type apiFixture struct {
-	ID                 int `json:"id"`
-	AwayTeamID         int `json:"team_a"`
-	HomeTeamID         int `json:"team_h"`
-	EventID            int `json:"event"`
-	AwayTeamDifficulty int `json:"team_a_difficulty"`
-	HomeTeamDifficulty int `json:"team_h_difficulty"`
+	ID                 int  `json:"id"`
+	AwayTeamID         int  `json:"team_a"`
+	HomeTeamID         int  `json:"team_h"`
+	EventID            int  `json:"event"`
+	AwayTeamDifficulty int  `json:"team_a_difficulty"`
+	HomeTeamDifficulty int  `json:"team_h_difficulty"`
+	Finished           bool `json:"finished"`
+	AwayTeamScore      *int `json:"team_a_score"`
+	HomeTeamScore      *int `json:"team_h_score"`
 }
 
 type apiFixtures []apiFixture
@@ -115,6 +140,22 @@ type apiEntryHistory struct {
 	Bank float32 `json:"bank"`
 }
 
+type apiEntryChips struct {
+	Chips []apiChip `json:"chips"`
+}
+
+type apiChip struct {
+	Name  string `json:"name"`
+	Event int    `json:"event"`
+}
+
+type apiTransfer struct {
+	ElementIn     int       `json:"element_in"`
+	ElementInCost int       `json:"element_in_cost"`
+	Event         int       `json:"event"`
+	Time          time.Time `json:"time"`
+}
+
 type Data struct {
 	PlayerTypes []PlayerType
 	Gameweeks   []Gameweek
@@ -204,20 +245,114 @@ func (d *Data) RequestManagerPicks(managerID int) TeamConfig {
 
 	gameweekPlayerSet := d.GameweekPlayerSet(d.CurrentGameweek().ID)
 
+	allPlayers := make(map[PlayerID]Player, len(d.Players))
+	for _, player := range d.Players {
+		allPlayers[player.ID] = player
+	}
+
 	players := make([]StartingPlayer, 0)
+	squad := make([]Player, 0)
 	for _, pick := range apiPicks.Picks {
 		thisPlayer, ok := gameweekPlayerSet[PlayerID(pick.Element)]
 		if ok {
 			players = append(players, thisPlayer)
 		}
+		if player, ok := allPlayers[PlayerID(pick.Element)]; ok {
+			squad = append(squad, player)
+		}
+	}
+
+	purchasePrices, err := requestPurchasePrices(managerID)
+	if err != nil {
+		panic(err)
+	}
+
+	sellingPrices := make(map[PlayerID]float32, len(squad))
+	for _, player := range squad {
+		purchasePrice, ok := purchasePrices[player.ID]
+		if !ok {
+			// never transferred in, so picked at the start of the season
+			purchasePrice = player.startingPrice()
+		}
+		sellingPrices[player.ID] = sellingPrice(purchasePrice, player.RawCost)
 	}
 
 	return TeamConfig{
-		Players:   players,
-		BankValue: apiPicks.EntryHistory.Bank,
+		Players: players,
+		Squad:   squad,
+		// the API gives the bank in tenths of a million
+		BankValue:     apiPicks.EntryHistory.Bank / 10,
+		SellingPrices: sellingPrices,
 	}
 }
 
+// PlayedChip is a chip the manager has already used.
+type PlayedChip struct {
+	Name     string
+	Gameweek GameweekID
+}
+
+func (d *Data) RequestManagerChips(managerID int) []PlayedChip {
+	endpoint := fmt.Sprintf("https://fantasy.premierleague.com/api/entry/%d/history/", managerID)
+
+	historyBody, err := getJsonBody(endpoint)
+	if err != nil {
+		panic(err)
+	}
+
+	var entryChips apiEntryChips
+	if err := json.Unmarshal(historyBody, &entryChips); err != nil {
+		panic(err)
+	}
+
+	played := make([]PlayedChip, 0, len(entryChips.Chips))
+	for _, chip := range entryChips.Chips {
+		played = append(played, PlayedChip{
+			Name:     chip.Name,
+			Gameweek: GameweekID(chip.Event),
+		})
+	}
+	return played
+}
+
+// requestPurchasePrices is what the manager last paid for each player they've
+// transferred in.
+func requestPurchasePrices(managerID int) (map[PlayerID]float32, error) {
+	endpoint := fmt.Sprintf("https://fantasy.premierleague.com/api/entry/%d/transfers/", managerID)
+
+	transfersBody, err := getJsonBody(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []apiTransfer
+	if err := json.Unmarshal(transfersBody, &transfers); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[PlayerID]float32, 0)
+	bought := make(map[PlayerID]time.Time, 0)
+	for _, transfer := range transfers {
+		playerID := PlayerID(transfer.ElementIn)
+		if last, ok := bought[playerID]; ok && last.After(transfer.Time) {
+			continue
+		}
+		bought[playerID] = transfer.Time
+		prices[playerID] = float32(transfer.ElementInCost) / 10
+	}
+	return prices, nil
+}
+
+// sellingPrice is what FPL pays for a player: the purchase price plus half of any
+// rise, rounded down to £0.1m, or the current price if it has fallen.
+func sellingPrice(purchasePrice float32, currentPrice float32) float32 {
+	if currentPrice <= purchasePrice {
+		return currentPrice
+	}
+	profit := int(math.Round(float64(currentPrice-purchasePrice)*10)) / 2
+	return roundMoney(purchasePrice + float32(profit)/10)
+}
+
 type PlayerTypeID int
 
 type PlayerType struct {
@@ -239,6 +374,7 @@ type PlayerStats struct {
 	YellowCards   int
 	RedCards      int
 	Bonus         int
+	Saves         int
 	Starts        int
 	AverageStarts float32
 	MatchesPlayed float32
@@ -270,14 +406,29 @@ type Player struct {
 	ChanceOfPlaying  PlayerRoundProbability
 	MostCaptained    bool
 	PickedPercentage float32
+	SetPieces        SetPieceOrders
 }
 
 type PlayerFixture struct {
-	FixtureID FixtureID
-	PlayerID  PlayerID
-	Minutes   int
-	Played    bool
-	Points    int
+	FixtureID     FixtureID
+	PlayerID      PlayerID
+	Gameweek      GameweekID
+	Kickoff       time.Time
+	Minutes       int
+	Played        bool
+	Started       bool
+	Points        int
+	Goals         int
+	Assists       int
+	CleanSheets   int
+	GoalsConceded int
+	Saves         int
+	YellowCards   int
+	RedCards      int
+	Bonus         int
+	BPS           int
+	// the player's price at the time
+	Price float32
 }
 
 type TeamID int
@@ -312,6 +463,9 @@ type Fixture struct {
 	HomeTeamDifficulty int
 	AwayTeamDifficulty int
 	DifficultyMajority int
+	Finished           bool
+	HomeTeamScore      int
+	AwayTeamScore      int
 }
 
 func (f *Fixture) Players() []Player {
@@ -456,6 +610,7 @@ func BuildData() (*Data, error) {
 				YellowCards:   apiPlayer.YellowCards,
 				RedCards:      apiPlayer.RedCards,
 				Bonus:         apiPlayer.Bonus,
+				Saves:         apiPlayer.Saves,
 				Starts:        apiPlayer.Starts,
 				AverageStarts: apiPlayer.StartsPerNinety,
 				ICTIndex:      float32(ictIndex),
@@ -463,6 +618,11 @@ func BuildData() (*Data, error) {
 			},
 			ChanceOfPlaying:  chanceOfPlaying,
 			PickedPercentage: float32(pickedPercentage),
+			SetPieces: SetPieceOrders{
+				Penalties:                   intOrZero(apiPlayer.PenaltiesOrder),
+				CornersAndIndirectFreekicks: intOrZero(apiPlayer.CornersAndIndirectOrder),
+				DirectFreekicks:             intOrZero(apiPlayer.DirectFreekicksOrder),
+			},
 		}
 
 		teamPlayersByID[newPlayer.Team.ID] = append(
@@ -506,6 +666,11 @@ func BuildData() (*Data, error) {
 			HomeTeamDifficulty: apiFixture.HomeTeamDifficulty,
 			AwayTeamDifficulty: apiFixture.AwayTeamDifficulty,
 			DifficultyMajority: abs(apiFixture.HomeTeamDifficulty - apiFixture.AwayTeamDifficulty),
+			Finished:           apiFixture.Finished,
+		}
+		if apiFixture.HomeTeamScore != nil && apiFixture.AwayTeamScore != nil {
+			newFixture.HomeTeamScore = *apiFixture.HomeTeamScore
+			newFixture.AwayTeamScore = *apiFixture.AwayTeamScore
 		}
 		fixtures = append(fixtures, &newFixture)
 
@@ -522,6 +687,89 @@ func BuildData() (*Data, error) {
 	return data, nil
 }
 
+// LoadPlayerHistory requests the match history of the given players, or of every
+// player if none are given, and attaches it to both data.Players and the players
+// held by each team. A player whose history can't be requested is left without one,
+// so their minutes are predicted from their season totals instead.
+func (d *Data) LoadPlayerHistory(playerIDs ...PlayerID) {
+	if len(playerIDs) == 0 {
+		for _, player := range d.Players {
+			playerIDs = append(playerIDs, player.ID)
+		}
+	}
+	names := make(map[PlayerID]string, len(d.Players))
+	for _, player := range d.Players {
+		names[player.ID] = player.Name
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	histories := make(map[PlayerID]map[FixtureID]PlayerFixture, len(playerIDs))
+	requests := make(chan PlayerID)
+
+	for i := 0; i < historyWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for playerID := range requests {
+				history, err := requestPlayerHistory(int(playerID))
+				mu.Lock()
+				if err != nil {
+					fmt.Printf("Couldn't load %s's match history, predicting their minutes from the season instead: %s\n", names[playerID], err)
+				} else {
+					histories[playerID] = history
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, playerID := range playerIDs {
+		requests <- playerID
+	}
+	close(requests)
+	wg.Wait()
+
+	for i := range d.Players {
+		if history, ok := histories[d.Players[i].ID]; ok {
+			d.Players[i].setHistory(history)
+		}
+	}
+	for _, team := range d.Teams {
+		for i := range team.Players {
+			if history, ok := histories[team.Players[i].ID]; ok {
+				team.Players[i].setHistory(history)
+			}
+		}
+	}
+}
+
+// startingPrice is the player's price in their first fixture of the season, or
+// their current price if they haven't had one.
+func (p *Player) startingPrice() float32 {
+	price := p.RawCost
+	var first time.Time
+	for _, fixture := range p.History {
+		if fixture.Price > 0 && (first.IsZero() || fixture.Kickoff.Before(first)) {
+			first = fixture.Kickoff
+			price = fixture.Price
+		}
+	}
+	return price
+}
+
+func (p *Player) setHistory(history map[FixtureID]PlayerFixture) {
+	p.History = history
+	p.Stats.MatchesPlayed = 0
+	for _, fixture := range history {
+		if fixture.Played {
+			p.Stats.MatchesPlayed++
+		}
+	}
+}
+
 func requestPlayerHistory(apiPlayerID int) (map[FixtureID]PlayerFixture, error) {
 	fixturesAndHistoryApiBody, err := getJsonBody(fmt.Sprintf("%s/%d", playerFixturesApi, apiPlayerID))
 	if err != nil {
@@ -534,11 +782,24 @@ func requestPlayerHistory(apiPlayerID int) (map[FixtureID]PlayerFixture, error)
 	fixturesToPlayerFixtures := make(map[FixtureID]PlayerFixture, 0)
 	for _, fixture := range fixturesAndHistory.History {
 		fixturesToPlayerFixtures[FixtureID(fixture.FixtureID)] = PlayerFixture{
-			FixtureID: FixtureID(fixture.FixtureID),
-			PlayerID:  PlayerID(fixture.ElementID),
-			Minutes:   fixture.Minutes,
-			Played:    fixture.Minutes > 0,
-			Points:    fixture.TotalPoints,
+			FixtureID:     FixtureID(fixture.FixtureID),
+			PlayerID:      PlayerID(fixture.ElementID),
+			Gameweek:      GameweekID(fixture.Round),
+			Kickoff:       fixture.KickoffTime,
+			Minutes:       fixture.Minutes,
+			Played:        fixture.Minutes > 0,
+			Started:       fixture.Starts > 0,
+			Points:        fixture.TotalPoints,
+			Goals:         fixture.Goals,
+			Assists:       fixture.Assists,
+			CleanSheets:   fixture.CleanSheets,
+			GoalsConceded: fixture.GoalsConceded,
+			Saves:         fixture.Saves,
+			YellowCards:   fixture.YellowCards,
+			RedCards:      fixture.RedCards,
+			Bonus:         fixture.Bonus,
+			BPS:           fixture.BPS,
+			Price:         float32(fixture.Value) / 10,
 		}
 	}
 
@@ -558,6 +819,13 @@ func getJsonBody(endpoint string) ([]byte, error) {
 	return body, nil
 }
 
+func intOrZero(x *int) int {
+	if x == nil {
+		return 0
+	}
+	return *x
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	// matches' worth of positional average BPS mixed into each player's own
+	bpsPriorMatches = 3
+	// shorter appearances are too noisy to turn into a per 90 rate
+	bpsMinimumMinutes = 30
+	bpsPriorDeviation = 8
+	// how many recent appearances the BPS model looks at
+	bpsWindow = 10
+)
+
+// BPS handed out for the events the simulation models. Everything else (passes,
+// tackles, key passes, recoveries...) is covered by each player's baseline.
+var bpsGoals = map[string]int{
+	"Goalkeeper": 12,
+	"Defender":   12,
+	"Midfielder": 18,
+	"Forward":    24,
+}
+
+// typical baseline BPS per 90 for each position, used when there's little history
+var bpsBaselinePrior = map[string]float64{
+	"Goalkeeper": 12,
+	"Defender":   14,
+	"Midfielder": 12,
+	"Forward":    8,
+}
+
+func eventBPS(position string, minutes float64, goals int, assists int, conceded int, saves int, yellowCards int, redCards int) float64 {
+	if minutes == 0 {
+		return 0
+	}
+	bps := 3
+	if minutes >= 60 {
+		bps = 6
+	}
+	bps += goals*bpsGoals[position] + assists*9 + saves/3*2 - yellowCards*3 - redCards*9
+	if position == "Goalkeeper" || position == "Defender" {
+		if minutes >= 60 && conceded == 0 {
+			bps += 12
+		}
+		bps -= conceded * 4
+	}
+	return float64(bps)
+}
+
+// bpsModel is a player's BPS per 90 from everything other than the modelled events.
+type bpsModel struct {
+	baselinePer90 float64
+	deviation     float64
+}
+
+func newBPSModel(player Player) bpsModel {
+	cacheKey := fmt.Sprintf("bps_player_%d", player.ID)
+	if val, exists := cache[cacheKey]; exists {
+		return val.(bpsModel)
+	}
+
+	position := player.Type.Name
+	prior := bpsBaselinePrior[position]
+
+	weightTotal := float64(bpsPriorMatches)
+	weightedSum := prior * bpsPriorMatches
+	weightedSquares := (prior*prior + bpsPriorDeviation*bpsPriorDeviation) * bpsPriorMatches
+
+	weight := 1.0
+	used := 0
+	for _, match := range recentMatches(player.History) {
+		if used == bpsWindow {
+			break
+		}
+		if match.Minutes < bpsMinimumMinutes {
+			continue
+		}
+		minutes := float64(match.Minutes)
+		baseline := float64(match.BPS) - eventBPS(position, minutes, match.Goals, match.Assists, match.GoalsConceded, match.Saves, match.YellowCards, match.RedCards)
+		per90 := baseline / minutes * 90
+
+		weightTotal += weight
+		weightedSum += weight * per90
+		weightedSquares += weight * per90 * per90
+		weight *= minutesRecencyDecay
+		used++
+	}
+
+	mean := weightedSum / weightTotal
+	model := bpsModel{
+		baselinePer90: mean,
+		deviation:     math.Sqrt(math.Max(0, weightedSquares/weightTotal-mean*mean)),
+	}
+
+	cache[cacheKey] = model
+
+	return model
+}
+
+func (m bpsModel) sample(rng *rand.Rand, minutes float64) float64 {
+	if minutes == 0 {
+		return 0
+	}
+	share := minutes / 90
+	return m.baselinePer90*share + rng.NormFloat64()*m.deviation*math.Sqrt(share)
+}
+
+// awardBonus gives 3, 2 and 1 bonus points to the highest BPS in the fixture. As in
+// FPL, tied players get the same bonus and push everyone below them down.
+func awardBonus(bps [][]float64, minutes [][]float64) [][]int {
+	bonus := make([][]int, len(bps))
+	for s := range bps {
+		bonus[s] = make([]int, len(bps[s]))
+		for i := range bps[s] {
+			if minutes[s][i] == 0 {
+				continue
+			}
+			rank := 0
+			for t := range bps {
+				for j := range bps[t] {
+					if minutes[t][j] > 0 && bps[t][j] > bps[s][i] {
+						rank++
+					}
+				}
+			}
+			if rank < 3 {
+				bonus[s][i] = 3 - rank
+			}
+		}
+	}
+	return bonus
+}
+
+// ExpectedBonus is the player's mean bonus across the simulated fixture.
+func (sp StartingPlayer) ExpectedBonus() float32 {
+	return simulateFixture(sp.Fixture, expectedPointsIterations).expectedBonus[sp.Player.ID]
+}
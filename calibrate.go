@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rodaine/table"
+)
+
+// Prediction is what the model thought of a player before a gameweek's deadline.
+type Prediction struct {
+	GameweekID      GameweekID
+	PlayerID        PlayerID
+	FixtureID       FixtureID
+	TypeID          PlayerTypeID
+	RawCost         float32
+	Score           float32
+	ExpectedPoints  *float32
+	ChanceOfPlaying float32
+	// raw value of each score factor, keyed by ScoreFactor.Name
+	Factors map[string]float32
+}
+
+func predictionsForGameweek(data *Data, gameweek int) []Prediction {
+	predictions := make([]Prediction, 0)
+	for _, player := range data.GameweekPlayers(gameweek) {
+		factors := make(map[string]float32, 0)
+		for _, factor := range player.ScoreFactors() {
+			factors[factor.Name] = factor.Value
+		}
+		expectedPoints := player.ExpectedPoints()
+		predictions = append(predictions, Prediction{
+			GameweekID:      GameweekID(gameweek),
+			PlayerID:        player.Player.ID,
+			FixtureID:       player.Fixture.ID,
+			TypeID:          player.Player.Type.ID,
+			RawCost:         player.Player.RawCost,
+			Score:           player.Score(),
+			ExpectedPoints:  &expectedPoints,
+			ChanceOfPlaying: player.Minutes().ChanceOfPlaying,
+			Factors:         factors,
+		})
+	}
+	return predictions
+}
+
+// runCalibration compares the predictions stored before a gameweek with what
+// happened, then keeps the results so the model can be tracked over the season.
+func runCalibration(data *Data, gameweekInt int) error {
+	gameweek := data.Gameweek(gameweekInt)
+	if gameweek == nil {
+		return fmt.Errorf("gameweek %d not found", gameweekInt)
+	}
+	if !gameweek.Finished {
+		fmt.Printf("\n%s hasn't finished yet\n\n", gameweek.Name)
+		return nil
+	}
+
+	store, err := OpenPlayerStore(gameweekInt)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	predictions, err := store.GetPredictions(gameweek.ID)
+	if err != nil {
+		return err
+	}
+	if len(predictions) == 0 {
+		fmt.Printf("\nNo predictions stored for %s, run with -save before the deadline\n\n", gameweek.Name)
+		return nil
+	}
+
+	results := calibrate(data, predictions)
+	if err := store.StoreCalibration(results); err != nil {
+		return err
+	}
+
+	history, err := store.GetCalibrations()
+	if err != nil {
+		return err
+	}
+
+	printCalibration(gameweek, results, history)
+
+	return nil
+}
+
+// CalibrationResult is one metric for one slice of the players, e.g. the rank
+// correlation for defenders.
+type CalibrationResult struct {
+	GameweekID GameweekID
+	Segment    string
+	Metric     string
+	Value      float64
+	SampleSize int
+}
+
+type calibrationSample struct {
+	prediction Prediction
+	points     float64
+	played     bool
+}
+
+func calibrate(data *Data, predictions []Prediction) []CalibrationResult {
+	playersByID := make(map[PlayerID]Player, len(data.Players))
+	for _, player := range data.Players {
+		playersByID[player.ID] = player
+	}
+
+	samples := make([]calibrationSample, 0, len(predictions))
+	for _, prediction := range predictions {
+		match, ok := playersByID[prediction.PlayerID].History[prediction.FixtureID]
+		if !ok {
+			// postponed, or the player has since left the league
+			continue
+		}
+		samples = append(samples, calibrationSample{
+			prediction: prediction,
+			points:     float64(match.Points),
+			played:     match.Played,
+		})
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	gameweekID := samples[0].prediction.GameweekID
+	results := make([]CalibrationResult, 0)
+	addResult := func(segment string, metric string, value float64, sampleSize int) {
+		if math.IsNaN(value) {
+			return
+		}
+		results = append(results, CalibrationResult{
+			GameweekID: gameweekID,
+			Segment:    segment,
+			Metric:     metric,
+			Value:      value,
+			SampleSize: sampleSize,
+		})
+	}
+
+	addSegment := func(segment string, segmentSamples []calibrationSample) {
+		scores := make([]float64, len(segmentSamples))
+		points := make([]float64, len(segmentSamples))
+		for i, sample := range segmentSamples {
+			scores[i] = float64(sample.prediction.Score)
+			points[i] = sample.points
+		}
+		addResult(segment, "rank_correlation", spearman(scores, points), len(segmentSamples))
+
+		var absoluteError float64
+		pointValued := 0
+		for _, sample := range segmentSamples {
+			if sample.prediction.ExpectedPoints != nil {
+				absoluteError += math.Abs(float64(*sample.prediction.ExpectedPoints) - sample.points)
+				pointValued++
+			}
+		}
+		if pointValued > 0 {
+			addResult(segment, "mean_absolute_error", absoluteError/float64(pointValued), pointValued)
+		}
+	}
+
+	addSegment("all", samples)
+
+	positionNames := make(map[PlayerTypeID]string, 0)
+	for _, playerType := range data.PlayerTypes {
+		positionNames[playerType.ID] = playerType.PluralName
+	}
+	samplesByPosition := make(map[string][]calibrationSample, 0)
+	samplesByPriceBand := make(map[string][]calibrationSample, 0)
+	for _, sample := range samples {
+		position := positionNames[sample.prediction.TypeID]
+		samplesByPosition[position] = append(samplesByPosition[position], sample)
+		band := priceBand(sample.prediction.RawCost)
+		samplesByPriceBand[band] = append(samplesByPriceBand[band], sample)
+	}
+	for _, playerType := range data.PlayerTypes {
+		addSegment(playerType.PluralName, samplesByPosition[playerType.PluralName])
+	}
+	for _, band := range priceBands {
+		addSegment(band.name, samplesByPriceBand[band.name])
+	}
+
+	// does each factor on its own still say anything about who scores?
+	if len(samples[0].prediction.Factors) > 0 {
+		factorNames := make([]string, 0)
+		for name := range samples[0].prediction.Factors {
+			factorNames = append(factorNames, name)
+		}
+		sort.Strings(factorNames)
+		points := make([]float64, len(samples))
+		for i, sample := range samples {
+			points[i] = sample.points
+		}
+		for _, name := range factorNames {
+			values := make([]float64, len(samples))
+			for i, sample := range samples {
+				values[i] = float64(sample.prediction.Factors[name])
+			}
+			addResult("factor: "+name, "rank_correlation", spearman(values, points), len(samples))
+		}
+	}
+
+	// how often players actually got on the pitch against the chance we gave them
+	var brier float64
+	for _, bucket := range chanceBuckets {
+		var predicted, observed float64
+		count := 0
+		for _, sample := range samples {
+			chance := float64(sample.prediction.ChanceOfPlaying)
+			if chance < bucket.from || chance >= bucket.to {
+				continue
+			}
+			predicted += chance
+			if sample.played {
+				observed++
+			}
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		addResult("chance "+bucket.name, "predicted_play_rate", predicted/float64(count), count)
+		addResult("chance "+bucket.name, "observed_play_rate", observed/float64(count), count)
+	}
+	for _, sample := range samples {
+		outcome := 0.0
+		if sample.played {
+			outcome = 1
+		}
+		brier += math.Pow(float64(sample.prediction.ChanceOfPlaying)-outcome, 2)
+	}
+	addResult("all", "chance_brier_score", brier/float64(len(samples)), len(samples))
+
+	return results
+}
+
+var priceBands = []struct {
+	name string
+	upTo float32
+}{
+	{"under £5.0m", 5},
+	{"£5.0m-£6.9m", 7},
+	{"£7.0m-£9.9m", 10},
+	{"£10.0m+", float32(math.Inf(1))},
+}
+
+func priceBand(rawCost float32) string {
+	for _, band := range priceBands {
+		if rawCost < band.upTo {
+			return band.name
+		}
+	}
+	return priceBands[len(priceBands)-1].name
+}
+
+var chanceBuckets = []struct {
+	name     string
+	from, to float64
+}{
+	{"0-24%", 0, 0.25},
+	{"25-49%", 0.25, 0.5},
+	{"50-74%", 0.5, 0.75},
+	{"75-99%", 0.75, 1},
+	{"100%", 1, 2},
+}
+
+// spearman is the rank correlation between x and y, using average ranks for ties.
+func spearman(x []float64, y []float64) float64 {
+	if len(x) < 3 || len(x) != len(y) {
+		return math.NaN()
+	}
+	return pearson(ranks(x), ranks(y))
+}
+
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+
+	ranked := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		averageRank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranked[order[k]] = averageRank
+		}
+		i = j + 1
+	}
+	return ranked
+}
+
+func pearson(x []float64, y []float64) float64 {
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(len(x))
+	meanY /= float64(len(y))
+
+	var covariance, varianceX, varianceY float64
+	for i := range x {
+		covariance += (x[i] - meanX) * (y[i] - meanY)
+		varianceX += (x[i] - meanX) * (x[i] - meanX)
+		varianceY += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return math.NaN()
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
+
+func printCalibration(gameweek *Gameweek, results []CalibrationResult, history []CalibrationResult) {
+	headerFmt, columnFmt := tableFormat()
+
+	fmt.Printf("\nHow the predictions for %s compare to the points scored:\n", gameweek.Name)
+	tbl := table.New("Segment", "Metric", "Value", "Players")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, result := range results {
+		tbl.AddRow(result.Segment, result.Metric, fmt.Sprintf("%.3f", result.Value), result.SampleSize)
+	}
+	tbl.Print()
+
+	// rank correlation of the score and each factor for every calibrated gameweek
+	trend := make(map[GameweekID]map[string]float64, 0)
+	segments := make([]string, 0)
+	seenSegments := make(map[string]bool, 0)
+	for _, result := range history {
+		if result.Metric != "rank_correlation" {
+			continue
+		}
+		if result.Segment != "all" && !strings.HasPrefix(result.Segment, "factor: ") {
+			continue
+		}
+		if trend[result.GameweekID] == nil {
+			trend[result.GameweekID] = make(map[string]float64, 0)
+		}
+		trend[result.GameweekID][result.Segment] = result.Value
+		if !seenSegments[result.Segment] {
+			seenSegments[result.Segment] = true
+			segments = append(segments, result.Segment)
+		}
+	}
+	if len(trend) < 2 {
+		fmt.Println()
+		return
+	}
+
+	gameweekIDs := make([]GameweekID, 0, len(trend))
+	for gameweekID := range trend {
+		gameweekIDs = append(gameweekIDs, gameweekID)
+	}
+	sort.Slice(gameweekIDs, func(i, j int) bool {
+		return gameweekIDs[i] < gameweekIDs[j]
+	})
+	sort.Strings(segments)
+
+	fmt.Printf("\nRank correlation with points across the season:\n")
+	headers := []interface{}{"Gameweek"}
+	for _, segment := range segments {
+		headers = append(headers, segment)
+	}
+	trendTbl := table.New(headers...)
+	trendTbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, gameweekID := range gameweekIDs {
+		row := []interface{}{gameweekID}
+		for _, segment := range segments {
+			value, ok := trend[gameweekID][segment]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, fmt.Sprintf("%.3f", value))
+		}
+		trendTbl.AddRow(row...)
+	}
+	trendTbl.Print()
+	fmt.Println()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rodaine/table"
+)
+
+const (
+	// expected points over a double gameweek worth spending the triple captain chip on
+	tripleCaptainThreshold = 12
+	// how many candidates the captaincy table lists
+	captainCandidates = 5
+)
+
+// CaptainCandidate is what captaining a player is likely to be worth.
+type CaptainCandidate struct {
+	Player   StartingPlayer
+	Fixtures int
+	// the player's expected points for the whole gameweek, which the armband doubles
+	ExpectedPoints float32
+	// 90th percentile of the player's simulated points
+	Ceiling float32
+	// a rough share of managers who'll score the player's points, counting captaincy twice
+	EffectiveOwnership float32
+	// the extra points captaining the player should earn over the average manager
+	Gain float32
+}
+
+type CaptainPick struct {
+	Profile     string
+	Captain     CaptainCandidate
+	ViceCaptain CaptainCandidate
+}
+
+type Captaincy struct {
+	// best first by expected points
+	Candidates []CaptainCandidate
+	Safe       CaptainPick
+	Aggressive CaptainPick
+	// nil when nobody has a good enough double gameweek
+	TripleCaptain *CaptainCandidate
+}
+
+// recommendCaptain ranks the team for the armband. The safe pick has the most expected
+// points, while the aggressive pick gains the most on everyone else, trading a little
+// expectation for a player fewer managers own or a higher ceiling.
+func recommendCaptain(players []StartingPlayer, fixtures []Fixture, mostCaptained PlayerID) Captaincy {
+	candidates := make([]CaptainCandidate, 0, len(players))
+	seen := make(map[PlayerID]bool, 0)
+	for _, player := range players {
+		// a player with two fixtures covers both of them
+		if seen[player.Player.ID] {
+			continue
+		}
+		seen[player.Player.ID] = true
+		candidates = append(candidates, newCaptainCandidate(player, fixtures, mostCaptained))
+	}
+
+	captaincy := Captaincy{}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Gain != candidates[j].Gain {
+			return candidates[i].Gain > candidates[j].Gain
+		}
+		return candidates[i].Ceiling > candidates[j].Ceiling
+	})
+	captaincy.Aggressive = pickCaptains("Aggressive", candidates)
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].ExpectedPoints > candidates[j].ExpectedPoints
+	})
+	captaincy.Safe = pickCaptains("Safe", candidates)
+	captaincy.Candidates = candidates
+
+	for i, candidate := range candidates {
+		if candidate.Fixtures > 1 && candidate.ExpectedPoints >= tripleCaptainThreshold {
+			captaincy.TripleCaptain = &candidates[i]
+			break
+		}
+	}
+
+	return captaincy
+}
+
+func newCaptainCandidate(player StartingPlayer, fixtures []Fixture, mostCaptained PlayerID) CaptainCandidate {
+	candidate := CaptainCandidate{Player: player}
+
+	// players with a double gameweek score in both fixtures
+	totals := make([]float32, expectedPointsIterations)
+	for _, fixture := range fixtures {
+		if fixture.HomeTeam.ID != player.Player.Team.ID && fixture.AwayTeam.ID != player.Player.Team.ID {
+			continue
+		}
+		candidate.Fixtures++
+		points := simulateFixture(fixture, expectedPointsIterations).points[player.Player.ID]
+		for i := range points {
+			totals[i] += points[i]
+		}
+	}
+	for _, total := range totals {
+		candidate.ExpectedPoints += total
+	}
+	candidate.ExpectedPoints /= float32(len(totals))
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i] < totals[j]
+	})
+	candidate.Ceiling = percentile(totals, 0.9)
+
+	// we don't know how many managers captain each player, so assume most owners of
+	// last week's most captained player will do so again
+	candidate.EffectiveOwnership = player.Player.PickedPercentage / 100
+	if player.Player.ID == mostCaptained {
+		candidate.EffectiveOwnership *= 2
+	}
+	candidate.Gain = candidate.ExpectedPoints * (2 - candidate.EffectiveOwnership)
+
+	return candidate
+}
+
+// pickCaptains takes the top candidate as captain and, where possible, a vice captain
+// from a different fixture so one postponement or rotation doesn't take out both.
+func pickCaptains(profile string, ranked []CaptainCandidate) CaptainPick {
+	pick := CaptainPick{Profile: profile}
+	if len(ranked) == 0 {
+		return pick
+	}
+	pick.Captain = ranked[0]
+	for i, candidate := range ranked[1:] {
+		if candidate.Player.Fixture.ID != pick.Captain.Player.Fixture.ID {
+			pick.ViceCaptain = candidate
+			return pick
+		}
+		if i == 0 {
+			pick.ViceCaptain = candidate
+		}
+	}
+	return pick
+}
+
+func printCaptaincy(captaincy Captaincy) {
+	headerFmt, columnFmt := tableFormat()
+
+	fmt.Printf("\nCaptaincy:\n")
+	tbl := table.New("Name", "xP (C)", "Ceiling (C)", "EO", "Gain", "Fixtures")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for i, candidate := range captaincy.Candidates {
+		if i == captainCandidates {
+			break
+		}
+		tbl.AddRow(
+			candidate.Player.Player.Name,
+			fmt.Sprintf("%.1f", candidate.ExpectedPoints*2),
+			fmt.Sprintf("%.0f", candidate.Ceiling*2),
+			fmt.Sprintf("%.0f%%", candidate.EffectiveOwnership*100),
+			fmt.Sprintf("%+.1f", candidate.Gain),
+			candidate.Fixtures,
+		)
+	}
+	tbl.Print()
+
+	fmt.Println()
+	for _, pick := range []CaptainPick{captaincy.Safe, captaincy.Aggressive} {
+		fmt.Printf("%s: captain %s, vice captain %s\n", pick.Profile, pick.Captain.Player.Player.Name, pick.ViceCaptain.Player.Player.Name)
+	}
+	if captaincy.TripleCaptain != nil {
+		fmt.Printf(
+			"Triple captain %s? They have %d fixtures and %.1f expected points.\n",
+			captaincy.TripleCaptain.Player.Player.Name,
+			captaincy.TripleCaptain.Fixtures,
+			captaincy.TripleCaptain.ExpectedPoints,
+		)
+	}
+}
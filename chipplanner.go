@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rodaine/table"
+)
+
+const (
+	benchBoostChip    = "bboost"
+	tripleCaptainChip = "3xc"
+	// every chip can be played once up to this gameweek and once after it
+	chipHalfwayGameweek = 19
+)
+
+// chips in the order the planner lists them, with their names
+var chipOrder = []string{benchBoostChip, tripleCaptainChip, freeHitChip, wildcardChip}
+
+var chipNames = map[string]string{
+	benchBoostChip:    "Bench Boost",
+	tripleCaptainChip: "Triple Captain",
+	freeHitChip:       "Free Hit",
+	wildcardChip:      "Wildcard",
+}
+
+// AvailableChip is a chip the manager still has and the gameweeks it can be played in.
+type AvailableChip struct {
+	Name string
+	From GameweekID
+	To   GameweekID
+}
+
+// ChipGameweek is what each chip is worth in a gameweek.
+type ChipGameweek struct {
+	Gameweek *Gameweek
+	Fixtures int
+	// teams with no fixture and teams with more than one
+	Blanks  int
+	Doubles int
+	Values  map[string]float32
+}
+
+type PlannedChip struct {
+	Chip     AvailableChip
+	Gameweek *Gameweek
+	Value    float32
+}
+
+type ChipSchedule struct {
+	Chips     []AvailableChip
+	Gameweeks []ChipGameweek
+	// in gameweek order
+	Plan []PlannedChip
+}
+
+// remainingChips is which chips the manager can still play from the gameweek on,
+// given the ones they've played. Each chip comes once in each half of the season.
+func remainingChips(played []PlayedChip, from GameweekID, last GameweekID) []AvailableChip {
+	halves := [][2]GameweekID{{1, chipHalfwayGameweek}, {chipHalfwayGameweek + 1, last}}
+
+	available := make([]AvailableChip, 0)
+	for _, half := range halves {
+		if half[1] < from {
+			continue
+		}
+		start := half[0]
+		if start < from {
+			start = from
+		}
+		for _, name := range chipOrder {
+			used := false
+			for _, chip := range played {
+				if chip.Name == name && chip.Gameweek >= half[0] && chip.Gameweek <= half[1] {
+					used = true
+				}
+			}
+			if !used {
+				available = append(available, AvailableChip{Name: name, From: start, To: half[1]})
+			}
+		}
+	}
+	return available
+}
+
+func chipAvailable(chips []AvailableChip, name string, gameweek GameweekID) bool {
+	for _, chip := range chips {
+		if chip.Name == name && gameweek >= chip.From && gameweek <= chip.To {
+			return true
+		}
+	}
+	return false
+}
+
+// planChips works out what each remaining chip would add in every gameweek left,
+// from the fixtures as they are now, so blanks, doubles and rescheduled matches are
+// all counted. Every value assumes the current squad: the bench's expected points
+// for a bench boost, the best player's for a triple captain, and the gain of the
+// best squad money can buy for a free hit's gameweek or the wildcard's horizon.
+// The schedule plays each chip in the gameweek that adds the most in total, no two
+// in the same gameweek.
+func (d *Data) planChips(config TeamConfig, played []PlayedChip, from GameweekID, wildcardHorizon int, constraints TeamConstraints) (ChipSchedule, error) {
+	planner := d.newTransferPlanner(from, len(d.Gameweeks), constraints)
+	if len(planner.gameweeks) == 0 {
+		return ChipSchedule{}, fmt.Errorf("there are no gameweeks left to play chips in")
+	}
+	last := planner.gameweeks[len(planner.gameweeks)-1].ID
+
+	schedule := ChipSchedule{
+		Chips: remainingChips(played, from, last),
+	}
+	if len(schedule.Chips) == 0 {
+		return schedule, nil
+	}
+	for week, gameweek := range planner.gameweeks {
+		chipGameweek := ChipGameweek{
+			Gameweek: gameweek,
+			Values:   make(map[string]float32, 0),
+		}
+
+		fixtureCounts := make(map[TeamID]int, 0)
+		for _, fixture := range d.FixturesByGameWeek(int(gameweek.ID)) {
+			chipGameweek.Fixtures++
+			fixtureCounts[fixture.HomeTeam.ID]++
+			fixtureCounts[fixture.AwayTeam.ID]++
+		}
+		for _, team := range d.Teams {
+			switch {
+			case fixtureCounts[team.ID] == 0:
+				chipGameweek.Blanks++
+			case fixtureCounts[team.ID] > 1:
+				chipGameweek.Doubles++
+			}
+		}
+
+		_, bench := planner.lineup(planner.pointsByType(config.Squad, week))
+		chipGameweek.Values[benchBoostChip] = bench
+
+		for _, player := range config.Squad {
+			if points := planner.expected[week][player.ID]; points > chipGameweek.Values[tripleCaptainChip] {
+				chipGameweek.Values[tripleCaptainChip] = points
+			}
+		}
+
+		if chipAvailable(schedule.Chips, freeHitChip, gameweek.ID) {
+			freeHit, err := planner.rebuild(d, freeHitChip, config, week, 1)
+			if err != nil {
+				return ChipSchedule{}, err
+			}
+			chipGameweek.Values[freeHitChip] = freeHit.Gain()
+		}
+		if chipAvailable(schedule.Chips, wildcardChip, gameweek.ID) {
+			wildcard, err := planner.rebuild(d, wildcardChip, config, week, wildcardHorizon)
+			if err != nil {
+				return ChipSchedule{}, err
+			}
+			chipGameweek.Values[wildcardChip] = wildcard.Gain()
+		}
+
+		schedule.Gameweeks = append(schedule.Gameweeks, chipGameweek)
+	}
+
+	schedule.Plan = scheduleChips(schedule.Chips, schedule.Gameweeks)
+	return schedule, nil
+}
+
+// scheduleChips tries every way of playing the chips in different gameweeks and
+// keeps the one worth the most.
+func scheduleChips(chips []AvailableChip, gameweeks []ChipGameweek) []PlannedChip {
+	var half []AvailableChip
+	var best []PlannedChip
+	var bestValue float32
+	used := make(map[GameweekID]bool, 0)
+	plan := make([]PlannedChip, 0, len(chips))
+
+	var next func(c int, value float32)
+	next = func(c int, value float32) {
+		if c == len(half) {
+			if best == nil || value > bestValue {
+				best = append([]PlannedChip{}, plan...)
+				bestValue = value
+			}
+			return
+		}
+		chip := half[c]
+		for _, gameweek := range gameweeks {
+			id := gameweek.Gameweek.ID
+			if id < chip.From || id > chip.To || used[id] {
+				continue
+			}
+			used[id] = true
+			plan = append(plan, PlannedChip{Chip: chip, Gameweek: gameweek.Gameweek, Value: gameweek.Values[chip.Name]})
+			next(c+1, value+gameweek.Values[chip.Name])
+			plan = plan[:len(plan)-1]
+			used[id] = false
+		}
+		// a chip might not fit if the other chips have taken every gameweek
+		next(c+1, value)
+	}
+
+	// the halves don't overlap, so each can be planned on its own
+	halves := make(map[GameweekID][]AvailableChip, 0)
+	ends := make([]GameweekID, 0)
+	for _, chip := range chips {
+		if _, ok := halves[chip.To]; !ok {
+			ends = append(ends, chip.To)
+		}
+		halves[chip.To] = append(halves[chip.To], chip)
+	}
+
+	planned := make([]PlannedChip, 0, len(chips))
+	for _, end := range ends {
+		half = halves[end]
+		best = nil
+		next(0, 0)
+		planned = append(planned, best...)
+	}
+	sort.SliceStable(planned, func(i, j int) bool {
+		return planned[i].Gameweek.ID < planned[j].Gameweek.ID
+	})
+	return planned
+}
+
+func printChipSchedule(schedule ChipSchedule) {
+	if len(schedule.Chips) == 0 {
+		fmt.Printf("\nYou've played all your chips.\n")
+		return
+	}
+	headerFmt, columnFmt := tableFormat()
+
+	planned := make(map[GameweekID]string, 0)
+	for _, chip := range schedule.Plan {
+		planned[chip.Gameweek.ID] = chip.Chip.Name
+	}
+
+	fmt.Printf("\nWhat each chip should add in the gameweeks left (* for the recommended week):\n")
+	headers := []interface{}{"Gameweek", "Fixtures", "Blanks", "Doubles"}
+	for _, name := range chipOrder {
+		headers = append(headers, chipNames[name])
+	}
+	tbl := table.New(headers...)
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, gameweek := range schedule.Gameweeks {
+		row := []interface{}{gameweek.Gameweek.Name, gameweek.Fixtures, gameweek.Blanks, gameweek.Doubles}
+		for _, name := range chipOrder {
+			cell := "-"
+			if chipAvailable(schedule.Chips, name, gameweek.Gameweek.ID) {
+				cell = fmt.Sprintf("%.1f", gameweek.Values[name])
+				if planned[gameweek.Gameweek.ID] == name {
+					cell += "*"
+				}
+			}
+			row = append(row, cell)
+		}
+		tbl.AddRow(row...)
+	}
+	tbl.Print()
+
+	fmt.Println()
+	lines := make([]string, 0, len(schedule.Plan))
+	for _, chip := range schedule.Plan {
+		lines = append(lines, fmt.Sprintf("%s in %s (%+.1f)", chipNames[chip.Chip.Name], chip.Gameweek.Name, chip.Value))
+	}
+	fmt.Printf("Recommended: %s\n", strings.Join(lines, ", "))
+}
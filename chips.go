@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rodaine/table"
+)
+
+const (
+	wildcardChip = "wildcard"
+	freeHitChip  = "freehit"
+)
+
+// ChipSquad is the squad to rebuild with a wildcard or free hit, compared with the
+// manager's current one.
+type ChipSquad struct {
+	Chip      string
+	Gameweeks []*Gameweek
+	Squad     Squad
+	// what the manager could spend, the bank plus the current squad's selling prices
+	Budget float32
+	Keep   []Player
+	In     []Player
+	Out    []Player
+	// each player's expected points over the gameweeks the chip is for
+	ExpectedPoints map[PlayerID]float32
+	// expected points for the new and current squads over those gameweeks
+	Points        float32
+	CurrentPoints float32
+}
+
+// Gain is how many more points the new squad should score.
+func (c ChipSquad) Gain() float32 {
+	return c.Points - c.CurrentPoints
+}
+
+// buildChipSquad picks the best squad money can buy for a free hit's gameweek or the
+// next horizon gameweeks of a wildcard. The current squad is priced at what it
+// would sell for, so keeping a player costs only what selling them would raise.
+func (d *Data) buildChipSquad(chip string, config TeamConfig, from GameweekID, horizon int, constraints TeamConstraints) (ChipSquad, error) {
+	switch chip {
+	case freeHitChip:
+		// the squad goes back to how it was after the gameweek
+		horizon = 1
+	case wildcardChip:
+		if horizon < 1 {
+			horizon = 1
+		}
+	default:
+		return ChipSquad{}, fmt.Errorf("-chip: expected %s or %s, got '%s'", wildcardChip, freeHitChip, chip)
+	}
+
+	planner := d.newTransferPlanner(from, horizon, constraints)
+	if len(planner.gameweeks) == 0 {
+		return ChipSquad{}, fmt.Errorf("there are no gameweeks left to play a %s in", chip)
+	}
+	return planner.rebuild(d, chip, config, 0, len(planner.gameweeks))
+}
+
+// rebuild picks the best squad for the planner's gameweeks from first, for as many
+// as count of them.
+func (p *transferPlanner) rebuild(d *Data, chip string, config TeamConfig, first int, count int) (ChipSquad, error) {
+	if first+count > len(p.gameweeks) {
+		count = len(p.gameweeks) - first
+	}
+	chipSquad := ChipSquad{
+		Chip:           chip,
+		Gameweeks:      p.gameweeks[first : first+count],
+		Budget:         config.SquadValue(),
+		ExpectedPoints: make(map[PlayerID]float32, 0),
+	}
+	for _, expected := range p.expected[first : first+count] {
+		for playerID, points := range expected {
+			chipSquad.ExpectedPoints[playerID] += points
+		}
+	}
+
+	// each player's first fixture over the horizon stands in for all of them
+	owned := make(map[PlayerID]Player, len(config.Squad))
+	for _, player := range config.SquadAtSellingPrices() {
+		owned[player.ID] = player
+	}
+	candidates := make([]StartingPlayer, 0)
+	seen := make(map[PlayerID]bool, 0)
+	for _, gameweek := range chipSquad.Gameweeks {
+		for _, player := range d.GameweekPlayers(int(gameweek.ID)) {
+			if seen[player.Player.ID] {
+				continue
+			}
+			seen[player.Player.ID] = true
+			if ownedPlayer, ok := owned[player.Player.ID]; ok {
+				player.Player = ownedPlayer
+			}
+			candidates = append(candidates, player)
+		}
+	}
+
+	value := func(sp StartingPlayer) float32 {
+		return chipSquad.ExpectedPoints[sp.Player.ID]
+	}
+	squad, err := optimiseSquad(p.playerTypes, candidates, value, chipSquad.Budget, p.constraints)
+	if err != nil {
+		return ChipSquad{}, err
+	}
+	chipSquad.Squad = squad
+
+	picked := make([]Player, 0)
+	pickedIDs := make(map[PlayerID]bool, 0)
+	for _, player := range append(squad.Starting.Players(), squad.Bench...) {
+		picked = append(picked, player.Player)
+		pickedIDs[player.Player.ID] = true
+		if _, ok := owned[player.Player.ID]; ok {
+			chipSquad.Keep = append(chipSquad.Keep, player.Player)
+		} else {
+			chipSquad.In = append(chipSquad.In, player.Player)
+		}
+	}
+	for _, player := range config.Squad {
+		if !pickedIDs[player.ID] {
+			chipSquad.Out = append(chipSquad.Out, player)
+		}
+	}
+
+	for week := first; week < first+count; week++ {
+		chipSquad.Points += p.squadPoints(picked, week)
+		chipSquad.CurrentPoints += p.squadPoints(config.Squad, week)
+	}
+
+	return chipSquad, nil
+}
+
+func printChipSquad(chipSquad ChipSquad) {
+	headerFmt, columnFmt := tableFormat()
+
+	first, last := chipSquad.Gameweeks[0], chipSquad.Gameweeks[len(chipSquad.Gameweeks)-1]
+	over := first.Name
+	if first != last {
+		over = fmt.Sprintf("%s to %s", first.Name, last.Name)
+	}
+	fmt.Printf("\nWith a %s and £%.1fm to spend, the best squad for %s is:\n", chipSquad.Chip, chipSquad.Budget, over)
+
+	kept := make(map[PlayerID]bool, 0)
+	for _, player := range chipSquad.Keep {
+		kept[player.ID] = true
+	}
+	players := append(chipSquad.Squad.Starting.Players(), chipSquad.Squad.Bench...)
+	sort.SliceStable(players, func(i, j int) bool {
+		return players[i].Player.Type.ID < players[j].Player.Type.ID
+	})
+
+	tbl := table.New("Type", "Name", "xP", "Cost", "")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, player := range players {
+		status := "In"
+		if kept[player.Player.ID] {
+			status = "Keep"
+		}
+		tbl.AddRow(
+			player.Player.Type.ShortName,
+			player.Player.Name,
+			fmt.Sprintf("%.1f", chipSquad.ExpectedPoints[player.Player.ID]),
+			fmt.Sprintf("£%.1fm", player.Player.RawCost),
+			status,
+		)
+	}
+	tbl.Print()
+
+	if len(chipSquad.In) > 0 {
+		fmt.Printf("\nOut: %s\n", playerNames(chipSquad.Out))
+		fmt.Printf("In: %s\n", playerNames(chipSquad.In))
+	} else {
+		fmt.Printf("\nYour current squad can't be beaten.\n")
+	}
+	fmt.Printf(
+		"\nThe squad costs £%.1fm, leaving £%.1fm in the bank, and should score %.1f points, %+.1f on your current squad.\n",
+		chipSquad.Squad.Cost,
+		chipSquad.Squad.Bank,
+		chipSquad.Points,
+		chipSquad.Gain(),
+	)
+}
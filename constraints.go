@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TeamConstraints are the user's rules for which players a team can have.
+type TeamConstraints struct {
+	// players who must be in the team
+	Locked map[PlayerID]Player
+	// players who can't be in the team
+	Excluded map[PlayerID]Player
+	// most players from any one club, 0 for FPL's limit of three
+	ClubCap int
+	// most players from particular clubs
+	ClubCaps map[TeamID]int
+	// most starters in particular positions
+	PositionCaps map[PlayerTypeID]int
+}
+
+// clubCap is the most players the team can have from the club.
+func (c TeamConstraints) clubCap(teamID TeamID) int {
+	if limit, ok := c.ClubCaps[teamID]; ok {
+		return limit
+	}
+	if c.ClubCap > 0 {
+		return c.ClubCap
+	}
+	return maxPlayersPerClub
+}
+
+// smallestClubCap is the tightest limit on any club.
+func (c TeamConstraints) smallestClubCap() int {
+	smallest := c.clubCap(0)
+	for _, limit := range c.ClubCaps {
+		if limit < smallest {
+			smallest = limit
+		}
+	}
+	return smallest
+}
+
+// allows is whether the formation keeps within the position caps.
+func (c TeamConstraints) allows(f formation) bool {
+	for typeID, limit := range c.PositionCaps {
+		if f[typeID] > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// check looks for the obvious reasons no team can meet the constraints: a locked
+// player who isn't available or more locked players than a club or position allows.
+// Starting is whether locked players have to start, rather than just be in the squad.
+func (c TeamConstraints) check(playerTypes []PlayerType, players []StartingPlayer, starting bool) error {
+	available := make(map[PlayerID]bool, 0)
+	for _, player := range players {
+		available[player.Player.ID] = true
+	}
+
+	lockedByClub := make(map[TeamID][]string, 0)
+	lockedByType := make(map[PlayerTypeID][]string, 0)
+	for _, player := range c.sortedLocked() {
+		if !available[player.ID] {
+			return fmt.Errorf("%s is locked but can't be picked this gameweek", player.Name)
+		}
+		lockedByClub[player.Team.ID] = append(lockedByClub[player.Team.ID], player.Name)
+		lockedByType[player.Type.ID] = append(lockedByType[player.Type.ID], player.Name)
+	}
+
+	for _, player := range c.sortedLocked() {
+		names := lockedByClub[player.Team.ID]
+		if limit := c.clubCap(player.Team.ID); len(names) > limit {
+			return fmt.Errorf("%s are locked but only %d can come from %s", strings.Join(names, ", "), limit, player.Team.Name)
+		}
+	}
+
+	for _, playerType := range playerTypes {
+		names := lockedByType[playerType.ID]
+		limit := playerType.TeamPlayerCount
+		if starting {
+			limit = playerType.TeamMaxPlayCount
+			if positionCap, ok := c.PositionCaps[playerType.ID]; ok && positionCap < limit {
+				limit = positionCap
+			}
+		}
+		if len(names) > limit {
+			return fmt.Errorf("%s are locked but only %d %s can be picked", strings.Join(names, ", "), limit, strings.ToLower(playerType.PluralName))
+		}
+	}
+
+	for _, playerType := range playerTypes {
+		if limit, ok := c.PositionCaps[playerType.ID]; ok && limit < playerType.TeamMinPlayCount {
+			return fmt.Errorf("at least %d %s have to start but the cap is %d", playerType.TeamMinPlayCount, strings.ToLower(playerType.PluralName), limit)
+		}
+	}
+
+	return nil
+}
+
+func (c TeamConstraints) sortedLocked() []Player {
+	locked := make([]Player, 0, len(c.Locked))
+	for _, player := range c.Locked {
+		locked = append(locked, player)
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].ID < locked[j].ID
+	})
+	return locked
+}
+
+// lockedAmong is the constraints with only the players given locked, for picking a
+// team from a squad that may not have everyone locked or may have them blanking.
+func (c TeamConstraints) lockedAmong(players []StartingPlayer) TeamConstraints {
+	locked := make(map[PlayerID]Player, 0)
+	for _, player := range players {
+		if lockedPlayer, ok := c.Locked[player.Player.ID]; ok {
+			locked[player.Player.ID] = lockedPlayer
+		}
+	}
+	c.Locked = locked
+	return c
+}
+
+// lockedOutside is the locked players who aren't in the squad, so have to be bought.
+func (c TeamConstraints) lockedOutside(squad []Player) []Player {
+	owned := make(map[PlayerID]bool, len(squad))
+	for _, player := range squad {
+		owned[player.ID] = true
+	}
+	outside := make([]Player, 0)
+	for _, player := range c.sortedLocked() {
+		if !owned[player.ID] {
+			outside = append(outside, player)
+		}
+	}
+	return outside
+}
+
+// buysAll is whether every one of the players is among those bought.
+func buysAll(bought []Player, players []Player) bool {
+	ids := make(map[PlayerID]bool, len(bought))
+	for _, player := range bought {
+		ids[player.ID] = true
+	}
+	for _, player := range players {
+		if !ids[player.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocked and isExcluded are for filtering candidates.
+func (c TeamConstraints) isLocked(player StartingPlayer) bool {
+	_, ok := c.Locked[player.Player.ID]
+	return ok
+}
+
+func (c TeamConstraints) isExcluded(player StartingPlayer) bool {
+	_, ok := c.Excluded[player.Player.ID]
+	return ok
+}
+
+// ParseTeamConstraints reads the -lock, -exclude, -club-cap and -position-cap flags.
+// Players are comma separated names, e.g. "Salah,Haaland". Caps are comma separated
+// too, either a number for every club or "ARS=1" for one club or position.
+func (d *Data) ParseTeamConstraints(lock string, exclude string, clubCap string, positionCap string) (TeamConstraints, error) {
+	constraints := TeamConstraints{
+		Locked:       make(map[PlayerID]Player, 0),
+		Excluded:     make(map[PlayerID]Player, 0),
+		ClubCaps:     make(map[TeamID]int, 0),
+		PositionCaps: make(map[PlayerTypeID]int, 0),
+	}
+
+	for _, name := range splitList(lock) {
+		player, ok := d.findPlayerByName(name)
+		if !ok {
+			return constraints, fmt.Errorf("-lock: player '%s' not found", name)
+		}
+		constraints.Locked[player.ID] = player
+	}
+	for _, name := range splitList(exclude) {
+		player, ok := d.findPlayerByName(name)
+		if !ok {
+			return constraints, fmt.Errorf("-exclude: player '%s' not found", name)
+		}
+		if _, ok := constraints.Locked[player.ID]; ok {
+			return constraints, fmt.Errorf("%s can't be both locked and excluded", player.Name)
+		}
+		constraints.Excluded[player.ID] = player
+	}
+
+	for _, item := range splitList(clubCap) {
+		name, limit, err := parseCap(item)
+		if err != nil {
+			return constraints, fmt.Errorf("-club-cap: %w", err)
+		}
+		if name == "" {
+			constraints.ClubCap = limit
+			continue
+		}
+		var team *Team
+		for _, t := range d.Teams {
+			if strings.EqualFold(t.Name, name) || strings.EqualFold(t.ShortName, name) {
+				team = t
+			}
+		}
+		if team == nil {
+			return constraints, fmt.Errorf("-club-cap: team '%s' not found", name)
+		}
+		constraints.ClubCaps[team.ID] = limit
+	}
+
+	for _, item := range splitList(positionCap) {
+		name, limit, err := parseCap(item)
+		if err != nil || name == "" {
+			return constraints, fmt.Errorf("-position-cap: expected e.g. FWD=2, got '%s'", item)
+		}
+		var playerType *PlayerType
+		for i, t := range d.PlayerTypes {
+			if strings.EqualFold(t.Name, name) || strings.EqualFold(t.ShortName, name) || strings.EqualFold(t.PluralName, name) {
+				playerType = &d.PlayerTypes[i]
+			}
+		}
+		if playerType == nil {
+			return constraints, fmt.Errorf("-position-cap: position '%s' not found", name)
+		}
+		constraints.PositionCaps[playerType.ID] = limit
+	}
+
+	return constraints, nil
+}
+
+// findPlayerByName is the most picked player whose name matches, as that's most
+// likely who was meant.
+func (d *Data) findPlayerByName(name string) (Player, bool) {
+	var found Player
+	ok := false
+	for _, player := range d.Players {
+		if !matchesName(player.Name, name) {
+			continue
+		}
+		if !ok || player.PickedPercentage > found.PickedPercentage {
+			found = player
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseCap reads "3" or "ARS=1", returning an empty name for the first.
+func parseCap(item string) (string, int, error) {
+	name, value, found := strings.Cut(item, "=")
+	if !found {
+		name, value = "", item
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || limit < 0 {
+		return "", 0, fmt.Errorf("'%s' isn't a valid cap", item)
+	}
+	return strings.TrimSpace(name), limit, nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
@@ -14,29 +15,155 @@ const (
 	dbName = "./players.sqlite"
 )
 
-func StoreData(data *Data, gameweekInt int) error {
-	store := PlayerStore{
-		GameweekID: gameweekInt,
-	}
-	store.Setup()
+// migrations build the schema one version at a time. Each runs once, in order, and
+// the database's user_version records how many have run, so new ones go on the end
+// and ones that have shipped are never changed.
+var migrations = []string{
+	// the tables from before migrations, as they were so existing databases carry on
+	`CREATE TABLE IF NOT EXISTS player_types (
+		id INTEGER PRIMARY KEY,
+		name TEXT,
+		plural_name TEXT,
+		short_name TEXT,
+		team_player_count INTEGER,
+		team_min_play_count INTEGER,
+		team_max_play_count INTEGER
+	);
+	CREATE TABLE IF NOT EXISTS players (
+		gameweek_player_id VARCHAR PRIMARY KEY,
+		id INTEGER,
+		gameweek_id INTEGER,
+		name TEXT,
+		form REAL,
+		points_per_game REAL,
+		total_points INTEGER,
+		cost TEXT,
+		raw_cost REAL,
+		team_id INTEGER,
+		type_id INTEGER,
+		minutes INTEGER,
+		goals INTEGER,
+		assists INTEGER,
+		conceded INTEGER,
+		clean_sheets INTEGER,
+		yellow_cards INTEGER,
+		red_cards INTEGER,
+		bonus INTEGER,
+		starts INTEGER,
+		average_starts REAL,
+		matches_played REAL,
+		ict_index REAL,
+		ict_index_rank INTEGER,
+		most_captained BOOLEAN,
+		picked_percentage REAL
+	);
+	CREATE TABLE IF NOT EXISTS predictions (
+		gameweek_id INTEGER,
+		player_id INTEGER,
+		fixture_id INTEGER,
+		type_id INTEGER,
+		raw_cost REAL,
+		score REAL,
+		expected_points REAL,
+		chance_of_playing REAL,
+		factors TEXT,
+		PRIMARY KEY (gameweek_id, player_id, fixture_id)
+	);
+	CREATE TABLE IF NOT EXISTS calibrations (
+		gameweek_id INTEGER,
+		segment TEXT,
+		metric TEXT,
+		value REAL,
+		sample_size INTEGER,
+		PRIMARY KEY (gameweek_id, segment, metric)
+	)`,
+	// a row for each gameweek saved, which every snapshot table hangs off
+	`CREATE TABLE snapshots (
+		gameweek_id INTEGER PRIMARY KEY,
+		saved_at TIMESTAMP NOT NULL
+	);
+	INSERT INTO snapshots (gameweek_id, saved_at)
+		SELECT DISTINCT gameweek_id, CURRENT_TIMESTAMP FROM players;
+	CREATE INDEX players_gameweek_id ON players (gameweek_id)`,
+	// everything else a snapshot needs to be the whole season as it was
+	`CREATE TABLE teams (
+		snapshot_gameweek_id INTEGER NOT NULL REFERENCES snapshots (gameweek_id) ON DELETE CASCADE,
+		id INTEGER NOT NULL,
+		name TEXT,
+		short_name TEXT,
+		PRIMARY KEY (snapshot_gameweek_id, id)
+	);
+	CREATE TABLE gameweeks (
+		snapshot_gameweek_id INTEGER NOT NULL REFERENCES snapshots (gameweek_id) ON DELETE CASCADE,
+		id INTEGER NOT NULL,
+		name TEXT,
+		deadline TEXT,
+		is_current BOOLEAN,
+		is_next BOOLEAN,
+		finished BOOLEAN,
+		most_captained_id INTEGER,
+		PRIMARY KEY (snapshot_gameweek_id, id)
+	);
+	CREATE TABLE fixtures (
+		snapshot_gameweek_id INTEGER NOT NULL,
+		id INTEGER NOT NULL,
+		gameweek_id INTEGER NOT NULL,
+		home_team_id INTEGER NOT NULL,
+		away_team_id INTEGER NOT NULL,
+		home_team_difficulty INTEGER,
+		away_team_difficulty INTEGER,
+		finished BOOLEAN,
+		home_team_score INTEGER,
+		away_team_score INTEGER,
+		PRIMARY KEY (snapshot_gameweek_id, id),
+		FOREIGN KEY (snapshot_gameweek_id, gameweek_id) REFERENCES gameweeks (snapshot_gameweek_id, id) ON DELETE CASCADE,
+		FOREIGN KEY (snapshot_gameweek_id, home_team_id) REFERENCES teams (snapshot_gameweek_id, id) ON DELETE CASCADE,
+		FOREIGN KEY (snapshot_gameweek_id, away_team_id) REFERENCES teams (snapshot_gameweek_id, id) ON DELETE CASCADE
+	);
+	CREATE INDEX fixtures_gameweek_id ON fixtures (snapshot_gameweek_id, gameweek_id);
+	CREATE INDEX fixtures_home_team_id ON fixtures (snapshot_gameweek_id, home_team_id);
+	CREATE INDEX fixtures_away_team_id ON fixtures (snapshot_gameweek_id, away_team_id);
+	CREATE TABLE player_fixtures (
+		snapshot_gameweek_id INTEGER NOT NULL,
+		player_id INTEGER NOT NULL,
+		fixture_id INTEGER NOT NULL,
+		gameweek_id INTEGER,
+		kickoff TIMESTAMP,
+		minutes INTEGER,
+		played BOOLEAN,
+		started BOOLEAN,
+		points INTEGER,
+		goals INTEGER,
+		assists INTEGER,
+		clean_sheets INTEGER,
+		goals_conceded INTEGER,
+		saves INTEGER,
+		yellow_cards INTEGER,
+		red_cards INTEGER,
+		bonus INTEGER,
+		bps INTEGER,
+		price REAL,
+		PRIMARY KEY (snapshot_gameweek_id, player_id, fixture_id),
+		FOREIGN KEY (snapshot_gameweek_id, fixture_id) REFERENCES fixtures (snapshot_gameweek_id, id) ON DELETE CASCADE
+	);
+	CREATE INDEX player_fixtures_fixture_id ON player_fixtures (snapshot_gameweek_id, fixture_id);
+	ALTER TABLE players ADD COLUMN saves INTEGER;
+	ALTER TABLE players ADD COLUMN chance_of_playing_this_round REAL;
+	ALTER TABLE players ADD COLUMN chance_of_playing_next_round REAL;
+	ALTER TABLE players ADD COLUMN penalties_order INTEGER;
+	ALTER TABLE players ADD COLUMN corners_and_indirect_freekicks_order INTEGER;
+	ALTER TABLE players ADD COLUMN direct_freekicks_order INTEGER`,
+}
 
-	for _, playerType := range data.PlayerTypes {
-		if err := store.StorePlayerType(playerType); err != nil {
-			return err
-		}
+func StoreData(data *Data, gameweekInt int) error {
+	store, err := OpenPlayerStore(gameweekInt)
+	if err != nil {
+		return err
 	}
+	defer store.Close()
 
-	// for _, team := range data.Teams {
-	// 	err := store.StoreTeam(team)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// }
-
-	for _, player := range data.Players {
-		if err := store.StorePlayer(player); err != nil {
-			return err
-		}
+	if err := store.SaveSnapshot(data); err != nil {
+		return err
 	}
 
 	if err := store.Dump(); err != nil {
@@ -51,24 +178,130 @@ type PlayerStore struct {
 	Connection *sql.DB
 }
 
-func (p *PlayerStore) Connect() (*sql.DB, error) {
-	conn, err := sql.Open("sqlite3", dbName)
+// OpenPlayerStore connects to the database for the gameweek and brings its schema
+// up to date. The connection stays open until Close.
+func OpenPlayerStore(gameweekInt int) (*PlayerStore, error) {
+	conn, err := sql.Open("sqlite3", dbName+"?_foreign_keys=on")
 	if err != nil {
 		return nil, err
 	}
-	p.Connection = conn
-	return conn, nil
+	// sqlite only allows one writer, so share one connection rather than wait on locks
+	conn.SetMaxOpenConns(1)
+
+	store := &PlayerStore{
+		GameweekID: gameweekInt,
+		Connection: conn,
+	}
+	if err := store.Migrate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return store, nil
 }
 
 func (p *PlayerStore) Close() error {
 	return p.Connection.Close()
 }
 
-func (p *PlayerStore) Dump() error {
-	// this pattern isn't working
-	p.Connect()
-	defer p.Close()
+// Migrate runs the migrations the database hasn't had yet, each in its own
+// transaction with the version it brings the database to.
+func (p *PlayerStore) Migrate() error {
+	var version int
+	if err := p.Connection.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("the database is at version %d, newer than this build knows (%d)", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		err := p.inTransaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[i]); err != nil {
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+			// pragmas can't take parameters
+			_, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inTransaction runs the writes in one transaction, so either all of them are kept
+// or none are.
+func (p *PlayerStore) inTransaction(write func(tx *sql.Tx) error) error {
+	tx, err := p.Connection.Begin()
+	if err != nil {
+		return err
+	}
+	if err := write(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
+// SaveSnapshot stores the data as the gameweek's snapshot, replacing one saved for
+// the gameweek before and leaving the other gameweeks' alone.
+func (p *PlayerStore) SaveSnapshot(data *Data) error {
+	return p.inTransaction(func(tx *sql.Tx) error {
+		// the snapshot's teams, gameweeks, fixtures and history go with it
+		if _, err := tx.Exec(`DELETE FROM snapshots WHERE gameweek_id = ?`, p.GameweekID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM players WHERE gameweek_id = ?`, p.GameweekID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO snapshots (gameweek_id, saved_at) VALUES (?, CURRENT_TIMESTAMP)`, p.GameweekID)
+		if err != nil {
+			return err
+		}
+
+		if err := p.StorePlayerTypes(tx, data.PlayerTypes); err != nil {
+			return err
+		}
+
+		if err := p.StoreTeams(tx, data.Teams); err != nil {
+			return err
+		}
+
+		if err := p.StoreGameweeks(tx, data.Gameweeks); err != nil {
+			return err
+		}
+
+		if err := p.StoreFixtures(tx, data.Fixtures); err != nil {
+			return err
+		}
+
+		// chances of playing are kept for the gameweek that was current and the one after
+		var current GameweekID
+		if gameweek := data.CurrentGameweek(); gameweek != nil {
+			current = gameweek.ID
+		}
+		if err := p.StorePlayers(tx, data.Players, current); err != nil {
+			return err
+		}
+
+		if err := p.StorePlayerFixtures(tx, data.Players, data.Fixtures); err != nil {
+			return err
+		}
+
+		// predictions are only worth keeping if they were made before the deadline
+		if gameweek := data.Gameweek(p.GameweekID); gameweek != nil && !gameweek.IsCurrent && !gameweek.Finished {
+			if err := p.StorePredictions(tx, predictionsForGameweek(data, p.GameweekID)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (p *PlayerStore) Dump() error {
 	exportDir := fmt.Sprintf("./exports/gw_%d", p.GameweekID)
 	err := os.Mkdir(exportDir, os.ModePerm)
 	if err != nil {
@@ -94,119 +327,576 @@ func (p *PlayerStore) Dump() error {
 	return nil
 }
 
-func (p *PlayerStore) Setup() error {
-	db, _ := p.Connect()
-	defer p.Close()
-
-	_, err := db.Exec(`DROP TABLE players; DROP TABLE player_types;`)
+func (p *PlayerStore) StorePlayers(tx *sql.Tx, players []Player, current GameweekID) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO players (gameweek_player_id, id, gameweek_id, name, form, points_per_game, total_points, cost, raw_cost, team_id, type_id, minutes, goals, assists, conceded, clean_sheets, yellow_cards, red_cards, bonus, starts, average_starts, matches_played, ict_index, ict_index_rank, most_captained, picked_percentage, saves, chance_of_playing_this_round, chance_of_playing_next_round, penalties_order, corners_and_indirect_freekicks_order, direct_freekicks_order)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
 
-	_, err = db.Exec(`CREATE TABLE player_types (
-		id INTEGER PRIMARY KEY,
-		name TEXT,
-		plural_name TEXT,
-		short_name TEXT,
-		team_player_count INTEGER,
-		team_min_play_count INTEGER,
-		team_max_play_count INTEGER
-	)`)
+	for _, player := range players {
+		chanceThisRound, ok := player.ChanceOfPlaying[current]
+		if !ok {
+			chanceThisRound = 1
+		}
+		chanceNextRound, ok := player.ChanceOfPlaying[current+1]
+		if !ok {
+			chanceNextRound = 1
+		}
+		_, err := stmt.Exec(fmt.Sprintf("%d_%d", p.GameweekID, player.ID), player.ID, p.GameweekID, player.Name, player.Form, player.PointsPerGame, player.TotalPoints, player.Cost, player.RawCost, player.Team.ID, player.Type.ID, player.Stats.Minutes, player.Stats.Goals, player.Stats.Assists, player.Stats.Conceded, player.Stats.CleanSheets, player.Stats.YellowCards, player.Stats.RedCards, player.Stats.Bonus, player.Stats.Starts, player.Stats.AverageStarts, player.Stats.MatchesPlayed, player.Stats.ICTIndex, player.Stats.ICTIndexRank, player.MostCaptained, player.PickedPercentage, player.Stats.Saves, chanceThisRound, chanceNextRound, player.SetPieces.Penalties, player.SetPieces.CornersAndIndirectFreekicks, player.SetPieces.DirectFreekicks)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+func (p *PlayerStore) StoreTeams(tx *sql.Tx, teams []*Team) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO teams (snapshot_gameweek_id, id, name, short_name)
+		VALUES (?, ?, ?, ?)
+	`)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
 
-	_, err = db.Exec(`CREATE TABLE players (
-		gameweek_player_id VARCHAR PRIMARY KEY,
-		id INTEGER,
-		gameweek_id INTEGER,
-		name TEXT,
-		form REAL,
-		points_per_game REAL,
-		total_points INTEGER,
-		cost TEXT,
-		raw_cost REAL,
-		team_id INTEGER,
-		type_id INTEGER,
-		minutes INTEGER,
-		goals INTEGER,
-		assists INTEGER,
-		conceded INTEGER,
-		clean_sheets INTEGER,
-		yellow_cards INTEGER,
-		red_cards INTEGER,
-		bonus INTEGER,
-		starts INTEGER,
-		average_starts REAL,
-		matches_played REAL,
-		ict_index REAL,
-		ict_index_rank INTEGER,
-		most_captained BOOLEAN,
-		picked_percentage REAL
-	)`)
+	for _, team := range teams {
+		if _, err := stmt.Exec(p.GameweekID, team.ID, team.Name, team.ShortName); err != nil {
+			return err
+		}
+	}
 
+	return nil
+}
+
+func (p *PlayerStore) StoreGameweeks(tx *sql.Tx, gameweeks []Gameweek) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO gameweeks (snapshot_gameweek_id, id, name, deadline, is_current, is_next, finished, most_captained_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
+
+	for _, gameweek := range gameweeks {
+		_, err := stmt.Exec(p.GameweekID, gameweek.ID, gameweek.Name, gameweek.Deadline, gameweek.IsCurrent, gameweek.IsNext, gameweek.Finished, gameweek.MostCaptainedID)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (p *PlayerStore) StorePlayer(player Player) error {
-	db, _ := p.Connect()
-	defer p.Close()
+func (p *PlayerStore) StoreFixtures(tx *sql.Tx, fixtures []*Fixture) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO fixtures (snapshot_gameweek_id, id, gameweek_id, home_team_id, away_team_id, home_team_difficulty, away_team_difficulty, finished, home_team_score, away_team_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
 
-	query := `
-		INSERT OR IGNORE INTO players (gameweek_player_id, id, gameweek_id, name, form, points_per_game, total_points, cost, raw_cost, team_id, type_id, minutes, goals, assists, conceded, clean_sheets, yellow_cards, red_cards, bonus, starts, average_starts, matches_played, ict_index, ict_index_rank, most_captained, picked_percentage)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+	for _, fixture := range fixtures {
+		_, err := stmt.Exec(p.GameweekID, fixture.ID, fixture.Gameweek.ID, fixture.HomeTeam.ID, fixture.AwayTeam.ID, fixture.HomeTeamDifficulty, fixture.AwayTeamDifficulty, fixture.Finished, fixture.HomeTeamScore, fixture.AwayTeamScore)
+		if err != nil {
+			return err
+		}
+	}
 
-	_, err := db.Exec(query, fmt.Sprintf("%d_%d", p.GameweekID, player.ID), player.ID, p.GameweekID, player.Name, player.Form, player.PointsPerGame, player.TotalPoints, player.Cost, player.RawCost, player.Team.ID, player.Type.ID, player.Stats.Minutes, player.Stats.Goals, player.Stats.Assists, player.Stats.Conceded, player.Stats.CleanSheets, player.Stats.YellowCards, player.Stats.RedCards, player.Stats.Bonus, player.Stats.Starts, player.Stats.AverageStarts, player.Stats.MatchesPlayed, player.Stats.ICTIndex, player.Stats.ICTIndexRank, player.MostCaptained, player.PickedPercentage)
+	return nil
+}
 
+// StorePlayerFixtures stores every player's match history. Matches in fixtures
+// that weren't stored, like ones without a gameweek, are left out.
+func (p *PlayerStore) StorePlayerFixtures(tx *sql.Tx, players []Player, fixtures []*Fixture) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO player_fixtures (snapshot_gameweek_id, player_id, fixture_id, gameweek_id, kickoff, minutes, played, started, points, goals, assists, clean_sheets, goals_conceded, saves, yellow_cards, red_cards, bonus, bps, price)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
+
+	stored := make(map[FixtureID]bool, len(fixtures))
+	for _, fixture := range fixtures {
+		stored[fixture.ID] = true
+	}
+
+	for _, player := range players {
+		for _, match := range player.History {
+			if !stored[match.FixtureID] {
+				continue
+			}
+			_, err := stmt.Exec(p.GameweekID, player.ID, match.FixtureID, match.Gameweek, match.Kickoff, match.Minutes, match.Played, match.Started, match.Points, match.Goals, match.Assists, match.CleanSheets, match.GoalsConceded, match.Saves, match.YellowCards, match.RedCards, match.Bonus, match.BPS, match.Price)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	return nil
 }
 
-func (p *PlayerStore) StorePlayerType(playerType PlayerType) error {
-	db, _ := p.Connect()
-	defer p.Close()
-
-	query := `
-		INSERT OR IGNORE INTO player_types (id, name, plural_name, short_name, team_player_count, team_min_play_count, team_max_play_count)
+func (p *PlayerStore) StorePlayerTypes(tx *sql.Tx, playerTypes []PlayerType) error {
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO player_types (id, name, plural_name, short_name, team_player_count, team_min_play_count, team_max_play_count)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
-
-	_, err := db.Exec(query, playerType.ID, playerType.Name, playerType.PluralName, playerType.ShortName, playerType.TeamPlayerCount, playerType.TeamMinPlayCount, playerType.TeamMaxPlayCount)
-
+	`)
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
+
+	for _, playerType := range playerTypes {
+		_, err := stmt.Exec(playerType.ID, playerType.Name, playerType.PluralName, playerType.ShortName, playerType.TeamPlayerCount, playerType.TeamMinPlayCount, playerType.TeamMaxPlayCount)
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (p *PlayerStore) GetPlayer(playerID PlayerID) (Player, error) {
-	db, _ := p.Connect()
-	defer p.Close()
+// playerColumns are read back in the order scanPlayer expects. Columns added since
+// the first snapshots fall back to what the API would have given.
+const playerColumns = `id, name, form, points_per_game, total_points, cost, raw_cost, team_id, type_id,
+	minutes, goals, assists, conceded, clean_sheets, yellow_cards, red_cards, bonus, starts, average_starts,
+	matches_played, ict_index, ict_index_rank, most_captained, picked_percentage, COALESCE(saves, 0),
+	COALESCE(chance_of_playing_this_round, 1), COALESCE(chance_of_playing_next_round, 1), COALESCE(penalties_order, 0),
+	COALESCE(corners_and_indirect_freekicks_order, 0), COALESCE(direct_freekicks_order, 0)`
+
+// storedPlayer is a player as read from the store, before their team and type are
+// linked up.
+type storedPlayer struct {
+	Player          Player
+	TeamID          TeamID
+	TypeID          PlayerTypeID
+	ChanceThisRound float32
+	ChanceNextRound float32
+}
 
-	row := db.QueryRow(fmt.Sprintf("SELECT * FROM `players` WHERE `id` = %d", playerID))
+// rowScanner is a single row or the current one of many.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	var player Player
+func scanPlayer(row rowScanner) (storedPlayer, error) {
+	var stored storedPlayer
+	player := &stored.Player
 	err := row.Scan(
 		&player.ID,
 		&player.Name,
+		&player.Form,
+		&player.PointsPerGame,
+		&player.TotalPoints,
+		&player.Cost,
+		&player.RawCost,
+		&stored.TeamID,
+		&stored.TypeID,
+		&player.Stats.Minutes,
+		&player.Stats.Goals,
+		&player.Stats.Assists,
+		&player.Stats.Conceded,
+		&player.Stats.CleanSheets,
+		&player.Stats.YellowCards,
+		&player.Stats.RedCards,
+		&player.Stats.Bonus,
+		&player.Stats.Starts,
+		&player.Stats.AverageStarts,
+		&player.Stats.MatchesPlayed,
+		&player.Stats.ICTIndex,
+		&player.Stats.ICTIndexRank,
+		&player.MostCaptained,
+		&player.PickedPercentage,
+		&player.Stats.Saves,
+		&stored.ChanceThisRound,
+		&stored.ChanceNextRound,
+		&player.SetPieces.Penalties,
+		&player.SetPieces.CornersAndIndirectFreekicks,
+		&player.SetPieces.DirectFreekicks,
 	)
+	return stored, err
+}
 
+// GetPlayer reads a player from the gameweek's snapshot with their team, type and
+// match history.
+func (p *PlayerStore) GetPlayer(playerID PlayerID) (Player, error) {
+	row := p.Connection.QueryRow(`SELECT `+playerColumns+` FROM players WHERE gameweek_id = ? AND id = ?`, p.GameweekID, playerID)
+	stored, err := scanPlayer(row)
 	if err != nil {
 		return Player{}, err
 	}
+	player := stored.Player
+
+	team := &Team{ID: stored.TeamID}
+	err = p.Connection.QueryRow(`SELECT name, short_name FROM teams WHERE snapshot_gameweek_id = ? AND id = ?`, p.GameweekID, stored.TeamID).
+		Scan(&team.Name, &team.ShortName)
+	if err != nil && err != sql.ErrNoRows {
+		return Player{}, err
+	}
+	player.Team = team
+
+	player.Type.ID = stored.TypeID
+	err = p.Connection.QueryRow(`SELECT name, plural_name, short_name, team_player_count, team_min_play_count, team_max_play_count FROM player_types WHERE id = ?`, stored.TypeID).
+		Scan(&player.Type.Name, &player.Type.PluralName, &player.Type.ShortName, &player.Type.TeamPlayerCount, &player.Type.TeamMinPlayCount, &player.Type.TeamMaxPlayCount)
+	if err != nil && err != sql.ErrNoRows {
+		return Player{}, err
+	}
+
+	histories, err := p.getPlayerFixtures(playerID)
+	if err != nil {
+		return Player{}, err
+	}
+	player.setHistory(histories[player.ID])
+
+	return player, nil
+}
+
+// LoadStoredData rebuilds the data as it was saved for the gameweek, in place of
+// BuildData.
+func LoadStoredData(gameweekInt int) (*Data, error) {
+	store, err := OpenPlayerStore(gameweekInt)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	return store.LoadData()
+}
+
+// LoadData rebuilds the gameweek's snapshot with everything linked up the way
+// BuildData does it: the fixtures point at the teams and gameweeks, and the teams
+// hold their players and fixtures.
+func (p *PlayerStore) LoadData() (*Data, error) {
+	var teamCount int
+	err := p.Connection.QueryRow(`SELECT COUNT(*) FROM teams WHERE snapshot_gameweek_id = ?`, p.GameweekID).Scan(&teamCount)
+	if err != nil {
+		return nil, err
+	}
+	if teamCount == 0 {
+		var snapshots int
+		err := p.Connection.QueryRow(`SELECT COUNT(*) FROM snapshots WHERE gameweek_id = ?`, p.GameweekID).Scan(&snapshots)
+		if err != nil {
+			return nil, err
+		}
+		if snapshots > 0 {
+			return nil, fmt.Errorf("the snapshot for gameweek %d was saved before fixtures were stored, save it again", p.GameweekID)
+		}
+		return nil, fmt.Errorf("there's no snapshot saved for gameweek %d", p.GameweekID)
+	}
+
+	data := &Data{}
+
+	playerTypesByID := make(map[PlayerTypeID]PlayerType, 0)
+	rows, err := p.Connection.Query(`
+		SELECT id, name, plural_name, short_name, team_player_count, team_min_play_count, team_max_play_count
+		FROM player_types
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var playerType PlayerType
+		err := rows.Scan(&playerType.ID, &playerType.Name, &playerType.PluralName, &playerType.ShortName, &playerType.TeamPlayerCount, &playerType.TeamMinPlayCount, &playerType.TeamMaxPlayCount)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		playerTypesByID[playerType.ID] = playerType
+		data.PlayerTypes = append(data.PlayerTypes, playerType)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var currentGameweekID GameweekID
+	gameweeksByID := make(map[GameweekID]*Gameweek, 0)
+	rows, err = p.Connection.Query(`
+		SELECT id, name, deadline, is_current, is_next, finished, most_captained_id
+		FROM gameweeks
+		WHERE snapshot_gameweek_id = ?
+		ORDER BY id
+	`, p.GameweekID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		gameweek := &Gameweek{}
+		err := rows.Scan(&gameweek.ID, &gameweek.Name, &gameweek.Deadline, &gameweek.IsCurrent, &gameweek.IsNext, &gameweek.Finished, &gameweek.MostCaptainedID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if gameweek.IsCurrent {
+			currentGameweekID = gameweek.ID
+		}
+		gameweeksByID[gameweek.ID] = gameweek
+		data.Gameweeks = append(data.Gameweeks, *gameweek)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	teamsByID := make(map[TeamID]*Team, 0)
+	rows, err = p.Connection.Query(`
+		SELECT id, name, short_name
+		FROM teams
+		WHERE snapshot_gameweek_id = ?
+		ORDER BY id
+	`, p.GameweekID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		team := &Team{}
+		if err := rows.Scan(&team.ID, &team.Name, &team.ShortName); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		teamsByID[team.ID] = team
+		data.Teams = append(data.Teams, team)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	histories, err := p.getPlayerFixtures(0)
+	if err != nil {
+		return nil, err
+	}
+
+	teamPlayersByID := make(map[TeamID][]Player, 0)
+	rows, err = p.Connection.Query(`SELECT `+playerColumns+` FROM players WHERE gameweek_id = ? ORDER BY id`, p.GameweekID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		stored, err := scanPlayer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		player := stored.Player
+
+		team, ok := teamsByID[stored.TeamID]
+		if !ok {
+			rows.Close()
+			return nil, fmt.Errorf("missing team ID '%d'", stored.TeamID)
+		}
+		playerType, ok := playerTypesByID[stored.TypeID]
+		if !ok {
+			rows.Close()
+			return nil, fmt.Errorf("missing player type ID '%d'", stored.TypeID)
+		}
+		player.Team = team
+		player.Type = playerType
+		player.ChanceOfPlaying = map[GameweekID]float32{
+			currentGameweekID:     stored.ChanceThisRound,
+			currentGameweekID + 1: stored.ChanceNextRound,
+		}
+		player.setHistory(histories[player.ID])
+
+		teamPlayersByID[team.ID] = append(teamPlayersByID[team.ID], player)
+		data.Players = append(data.Players, player)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, team := range data.Teams {
+		team.Players = teamPlayersByID[team.ID]
+	}
+
+	rows, err = p.Connection.Query(`
+		SELECT id, gameweek_id, home_team_id, away_team_id, home_team_difficulty, away_team_difficulty, finished, home_team_score, away_team_score
+		FROM fixtures
+		WHERE snapshot_gameweek_id = ?
+		ORDER BY id
+	`, p.GameweekID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var fixture Fixture
+		var gameweekID GameweekID
+		var homeTeamID, awayTeamID TeamID
+		err := rows.Scan(&fixture.ID, &gameweekID, &homeTeamID, &awayTeamID, &fixture.HomeTeamDifficulty, &fixture.AwayTeamDifficulty, &fixture.Finished, &fixture.HomeTeamScore, &fixture.AwayTeamScore)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		fixture.Gameweek = gameweeksByID[gameweekID]
+		fixture.HomeTeam = teamsByID[homeTeamID]
+		fixture.AwayTeam = teamsByID[awayTeamID]
+		fixture.DifficultyMajority = abs(fixture.HomeTeamDifficulty - fixture.AwayTeamDifficulty)
+
+		data.Fixtures = append(data.Fixtures, &fixture)
+		fixture.HomeTeam.Fixtures = append(fixture.HomeTeam.Fixtures, fixture)
+		fixture.AwayTeam.Fixtures = append(fixture.AwayTeam.Fixtures, fixture)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// getPlayerFixtures reads the snapshot's match histories by player, for just the
+// one player or everyone if the ID is 0.
+func (p *PlayerStore) getPlayerFixtures(playerID PlayerID) (map[PlayerID]map[FixtureID]PlayerFixture, error) {
+	rows, err := p.Connection.Query(`
+		SELECT player_id, fixture_id, gameweek_id, kickoff, minutes, played, started, points, goals, assists, clean_sheets, goals_conceded, saves, yellow_cards, red_cards, bonus, bps, price
+		FROM player_fixtures
+		WHERE snapshot_gameweek_id = ? AND (? = 0 OR player_id = ?)
+	`, p.GameweekID, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histories := make(map[PlayerID]map[FixtureID]PlayerFixture, 0)
+	for rows.Next() {
+		var match PlayerFixture
+		err := rows.Scan(&match.PlayerID, &match.FixtureID, &match.Gameweek, &match.Kickoff, &match.Minutes, &match.Played, &match.Started, &match.Points, &match.Goals, &match.Assists, &match.CleanSheets, &match.GoalsConceded, &match.Saves, &match.YellowCards, &match.RedCards, &match.Bonus, &match.BPS, &match.Price)
+		if err != nil {
+			return nil, err
+		}
+		if histories[match.PlayerID] == nil {
+			histories[match.PlayerID] = make(map[FixtureID]PlayerFixture, 0)
+		}
+		histories[match.PlayerID][match.FixtureID] = match
+	}
+
+	return histories, rows.Err()
+}
+
+func (p *PlayerStore) StorePredictions(tx *sql.Tx, predictions []Prediction) error {
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO predictions (gameweek_id, player_id, fixture_id, type_id, raw_cost, score, expected_points, chance_of_playing, factors)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, prediction := range predictions {
+		factors, err := json.Marshal(prediction.Factors)
+		if err != nil {
+			return err
+		}
+
+		_, err = stmt.Exec(prediction.GameweekID, prediction.PlayerID, prediction.FixtureID, prediction.TypeID, prediction.RawCost, prediction.Score, prediction.ExpectedPoints, prediction.ChanceOfPlaying, string(factors))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *PlayerStore) GetPredictions(gameweekID GameweekID) ([]Prediction, error) {
+	rows, err := p.Connection.Query(`
+		SELECT gameweek_id, player_id, fixture_id, type_id, raw_cost, score, expected_points, chance_of_playing, factors
+		FROM predictions
+		WHERE gameweek_id = ?
+	`, gameweekID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	predictions := make([]Prediction, 0)
+	for rows.Next() {
+		var prediction Prediction
+		var expectedPoints sql.NullFloat64
+		var factors string
+		err := rows.Scan(
+			&prediction.GameweekID,
+			&prediction.PlayerID,
+			&prediction.FixtureID,
+			&prediction.TypeID,
+			&prediction.RawCost,
+			&prediction.Score,
+			&expectedPoints,
+			&prediction.ChanceOfPlaying,
+			&factors,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if expectedPoints.Valid {
+			points := float32(expectedPoints.Float64)
+			prediction.ExpectedPoints = &points
+		}
+		if err := json.Unmarshal([]byte(factors), &prediction.Factors); err != nil {
+			return nil, err
+		}
+		predictions = append(predictions, prediction)
+	}
+
+	return predictions, rows.Err()
+}
+
+func (p *PlayerStore) StoreCalibration(results []CalibrationResult) error {
+	return p.inTransaction(func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(`
+			INSERT OR REPLACE INTO calibrations (gameweek_id, segment, metric, value, sample_size)
+			VALUES (?, ?, ?, ?, ?)
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, result := range results {
+			_, err := stmt.Exec(result.GameweekID, result.Segment, result.Metric, result.Value, result.SampleSize)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetCalibrations returns the stored results for every gameweek, oldest first.
+func (p *PlayerStore) GetCalibrations() ([]CalibrationResult, error) {
+	rows, err := p.Connection.Query(`
+		SELECT gameweek_id, segment, metric, value, sample_size
+		FROM calibrations
+		ORDER BY gameweek_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]CalibrationResult, 0)
+	for rows.Next() {
+		var result CalibrationResult
+		err := rows.Scan(&result.GameweekID, &result.Segment, &result.Metric, &result.Value, &result.SampleSize)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
 
-	fmt.Printf("player: %+v", player)
-	return Player{}, nil
+	return results, rows.Err()
 }
 
 // getTableNames retrieves a list of table names from the SQLite database.
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rodaine/table"
+)
+
+// FactorExplanation is a score factor alongside how much it moved the score and
+// how it compares to other players of the same type.
+type FactorExplanation struct {
+	ScoreFactor
+	// signed share of the score on a log scale, negative factors pull the score down
+	Contribution float32
+	Percentile   float32
+}
+
+type ScoreExplanation struct {
+	Player  StartingPlayer
+	Score   float32
+	Factors []FactorExplanation
+}
+
+func explainScore(sp StartingPlayer, population []StartingPlayer) ScoreExplanation {
+	factors := sp.ScoreFactors()
+	contributions := factorContributions(factors)
+
+	sameTypeFactors := make([][]ScoreFactor, 0)
+	for _, other := range population {
+		if other.Player.Type.ID == sp.Player.Type.ID {
+			sameTypeFactors = append(sameTypeFactors, other.ScoreFactors())
+		}
+	}
+
+	explanation := ScoreExplanation{
+		Player: sp,
+		Score:  sp.Score(),
+	}
+	for i, factor := range factors {
+		var below, equal float32
+		for _, otherFactors := range sameTypeFactors {
+			switch {
+			case otherFactors[i].Value < factor.Value:
+				below++
+			case otherFactors[i].Value == factor.Value:
+				equal++
+			}
+		}
+		var percentile float32
+		if len(sameTypeFactors) > 0 {
+			percentile = (below + equal/2) / float32(len(sameTypeFactors)) * 100
+		}
+		explanation.Factors = append(explanation.Factors, FactorExplanation{
+			ScoreFactor:  factor,
+			Contribution: contributions[i],
+			Percentile:   percentile,
+		})
+	}
+
+	return explanation
+}
+
+// factorContributions splits log(score) between the factors. A factor of zero wipes
+// out the whole score, so it takes all of the (negative) contribution.
+func factorContributions(factors []ScoreFactor) []float32 {
+	contributions := make([]float32, len(factors))
+
+	var total float64
+	zeroed := false
+	for _, factor := range factors {
+		if factor.Multiplier <= 0 {
+			zeroed = true
+			continue
+		}
+		total += math.Abs(math.Log(float64(factor.Multiplier)))
+	}
+
+	for i, factor := range factors {
+		switch {
+		case zeroed:
+			if factor.Multiplier <= 0 {
+				contributions[i] = -1
+			}
+		case total > 0:
+			contributions[i] = float32(math.Log(float64(factor.Multiplier)) / total)
+		}
+	}
+
+	return contributions
+}
+
+func printScoreExplanation(explanation ScoreExplanation) {
+	headerFmt, columnFmt := tableFormat()
+	tbl := table.New("Factor", "Value", "Multiplier", "Contribution", fmt.Sprintf("Percentile (%s)", explanation.Player.Player.Type.PluralName))
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, factor := range explanation.Factors {
+		tbl.AddRow(
+			factor.Name,
+			fmt.Sprintf(factor.Format, factor.Value),
+			fmt.Sprintf("x%.2f", factor.Multiplier),
+			fmt.Sprintf("%+.0f%%", factor.Contribution*100),
+			fmt.Sprintf("%.0f", factor.Percentile),
+		)
+	}
+	fmt.Println()
+	tbl.Print()
+	fmt.Println()
+	fmt.Println("(Contribution = share of the score on a log scale, negative factors pull the score down)")
+}
+
+func printTeamExplanation(players []StartingPlayer, population []StartingPlayer) {
+	if len(players) == 0 {
+		return
+	}
+
+	headerFmt, columnFmt := tableFormat()
+	headers := []interface{}{"Name"}
+	for _, factor := range players[0].ScoreFactors() {
+		headers = append(headers, factor.Name)
+	}
+	headers = append(headers, "Score")
+
+	tbl := table.New(headers...)
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, player := range players {
+		explanation := explainScore(player, population)
+		row := []interface{}{player.Player.Name}
+		for _, factor := range explanation.Factors {
+			row = append(row, fmt.Sprintf(
+				factor.Format+" (%+.0f%%, p%.0f)",
+				factor.Value,
+				factor.Contribution*100,
+				factor.Percentile,
+			))
+		}
+		row = append(row, fmt.Sprintf("%.0f", explanation.Score))
+		tbl.AddRow(row...)
+	}
+	tbl.Print()
+	fmt.Println()
+	fmt.Println("(Each factor shows: value (contribution on a log scale, percentile within position))")
+}
+
+// printScoreComparison shows which factors put the higher scoring of the two players
+// ahead of the other, biggest difference first.
+func printScoreComparison(a StartingPlayer, b StartingPlayer) {
+	if b.Score() > a.Score() {
+		a, b = b, a
+	}
+	aFactors := a.ScoreFactors()
+	bFactors := b.ScoreFactors()
+
+	type factorDiff struct {
+		name         string
+		aValue       string
+		bValue       string
+		ratio        float64
+		logRatioSize float64
+	}
+
+	diffs := make([]factorDiff, 0, len(aFactors))
+	for i := range aFactors {
+		var ratio, logRatioSize float64
+		switch {
+		case aFactors[i].Multiplier == bFactors[i].Multiplier:
+			ratio = 1
+		case bFactors[i].Multiplier <= 0:
+			ratio, logRatioSize = math.Inf(1), math.Inf(1)
+		case aFactors[i].Multiplier <= 0:
+			ratio, logRatioSize = 0, math.Inf(1)
+		default:
+			ratio = float64(aFactors[i].Multiplier / bFactors[i].Multiplier)
+			logRatioSize = math.Abs(math.Log(ratio))
+		}
+		diffs = append(diffs, factorDiff{
+			name:         aFactors[i].Name,
+			aValue:       fmt.Sprintf(aFactors[i].Format, aFactors[i].Value),
+			bValue:       fmt.Sprintf(bFactors[i].Format, bFactors[i].Value),
+			ratio:        ratio,
+			logRatioSize: logRatioSize,
+		})
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return diffs[i].logRatioSize > diffs[j].logRatioSize
+	})
+
+	fmt.Printf("\nWhy %s (%.0f) over %s (%.0f):\n", a.Player.Name, a.Score(), b.Player.Name, b.Score())
+	headerFmt, columnFmt := tableFormat()
+	tbl := table.New("Factor", a.Player.Name, b.Player.Name, "Ratio", "Favours")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, diff := range diffs {
+		favours := "-"
+		if diff.ratio > 1 {
+			favours = a.Player.Name
+		} else if diff.ratio < 1 {
+			favours = b.Player.Name
+		}
+		ratio := fmt.Sprintf("x%.2f", diff.ratio)
+		if math.IsInf(diff.ratio, 1) {
+			ratio = "-"
+		}
+		tbl.AddRow(diff.name, diff.aValue, diff.bValue, ratio, favours)
+	}
+	tbl.Print()
+	fmt.Println()
+	fmt.Println("(Ratio = how many times more the factor multiplies the first player's score by)")
+}
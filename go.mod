@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/fatih/color v1.15.0
 	github.com/lithammer/fuzzysearch v1.1.8
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/rodaine/table v1.1.0
 	golang.org/x/text v0.12.0
 )
@@ -12,6 +13,5 @@ require (
 require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.17 // indirect
-	github.com/mattn/go-sqlite3 v1.14.17 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 )
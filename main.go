@@ -17,6 +17,20 @@ import (
 
 var cache = make(map[string]interface{}, 0)
 
+// ScoringConfig holds the tunable parts of the scoring model, set from flags.
+type ScoringConfig struct {
+	// how much a first choice penalty taker's score is increased by, other set pieces are worth less
+	SetPieceUplift float32
+	// how many standard deviations are taken off a score when picking teams
+	RiskAversion float32
+	// fixture difficulties worked out from results, FPL's are used when nil
+	Ratings *FixtureRatings
+}
+
+var scoringConfig = ScoringConfig{
+	SetPieceUplift: 0.15,
+}
+
 type StartingPlayer struct {
 	Player       Player
 	Fixture      Fixture
@@ -26,45 +40,57 @@ type StartingPlayer struct {
 }
 
 func (sp StartingPlayer) Score() float32 {
-	cacheKey := fmt.Sprintf("score_player_%d", sp.Player.ID)
+	cacheKey := fmt.Sprintf("score_player_%d_%d", sp.Player.ID, sp.Fixture.ID)
 	if val, exists := cache[cacheKey]; exists {
 		return val.(float32)
 	}
 
-	chanceOfPlaying, ok := sp.Player.ChanceOfPlaying[sp.Fixture.Gameweek.ID]
-	if !ok {
-		chanceOfPlaying = 1
+	score := float32(1)
+	for _, factor := range sp.ScoreFactors() {
+		score *= factor.Multiplier
 	}
 
-	// i'm thinking that this prevents multiplying by 0 and by 1 has no effect anyway
-	difficultyMajority := float32(sp.Fixture.DifficultyMajority + 1)
-
-	score := sp.Player.Form *
-		sp.Player.Stats.ICTIndex *
-		difficultyMajority *
-		sp.Player.Stats.AverageStarts *
-		sp.Player.PointsPerGame *
-		chanceOfPlaying
-
 	cache[cacheKey] = score
 
 	return score
 }
 
+// ScoreFactor is one of the terms multiplied together to make a player's score.
+type ScoreFactor struct {
+	Name       string
+	Value      float32
+	Multiplier float32
+	Format     string
+}
+
+func (sp StartingPlayer) ScoreFactors() []ScoreFactor {
+	minutes := sp.Minutes()
+	expectedBonus := sp.ExpectedBonus()
+	setPieces := sp.Player.SetPieces.Weight()
+
+	return []ScoreFactor{
+		{Name: "Form", Value: sp.Player.Form, Multiplier: sp.Player.Form, Format: "%.1f"},
+		{Name: "ICT", Value: sp.Player.Stats.ICTIndex, Multiplier: sp.Player.Stats.ICTIndex, Format: "%.1f"},
+		{Name: "Difficulty", Value: sp.Fixture.DifficultyEdge(sp.Player.Team.ID), Multiplier: sp.difficultyMultiplier(), Format: "%+.0f"},
+		{Name: "Minutes", Value: minutes.AvailableMinutes, Multiplier: minutes.AvailableMinutes / 90, Format: "%.0f"},
+		{Name: "PPG", Value: sp.Player.PointsPerGame, Multiplier: sp.Player.PointsPerGame, Format: "%.2f"},
+		{Name: "Chance", Value: minutes.ChanceOfPlaying * 100, Multiplier: minutes.ChanceOfPlaying, Format: "%.0f%%"},
+		{Name: "Bonus", Value: expectedBonus, Multiplier: 1 + expectedBonus, Format: "%.2f"},
+		{Name: "Set pieces", Value: setPieces, Multiplier: 1 + setPieces*scoringConfig.SetPieceUplift, Format: "%.2f"},
+	}
+}
+
 func (sp StartingPlayer) WeightedPointsAverage() float32 {
-	cacheKey := fmt.Sprintf("wppg_player_%d", sp.Player.ID)
+	cacheKey := fmt.Sprintf("wppg_player_%d_%d", sp.Player.ID, sp.Fixture.ID)
 	if val, exists := cache[cacheKey]; exists {
 		return val.(float32)
 	}
 
-	// we load this here because it's very slow to make this request for all players
-	playerHistory, _ := requestPlayerHistory(int(sp.Player.ID))
-
 	// get all matches with similar difficulty majority
 	teamFixtures := sp.Player.Team.Fixtures
 	similarTeamFixtures := make(map[FixtureID]bool, 0)
 	for _, fixture := range teamFixtures {
-		if fixture.DifficultyMajority == sp.Fixture.DifficultyMajority {
+		if fixture.Majority() == sp.Fixture.Majority() {
 			similarTeamFixtures[fixture.ID] = true
 		}
 	}
@@ -75,7 +101,7 @@ func (sp StartingPlayer) WeightedPointsAverage() float32 {
 
 	totalPoints := 0
 	similarFixturesPlayerPlayedIn := 0
-	for fixtureID, fixture := range playerHistory {
+	for fixtureID, fixture := range sp.Player.History {
 		if _, ok := similarTeamFixtures[fixtureID]; ok {
 			totalPoints += fixture.Points
 			similarFixturesPlayerPlayedIn++
@@ -93,28 +119,6 @@ func (sp StartingPlayer) WeightedPointsAverage() float32 {
 	return returnVal
 }
 
-type StartingEleven map[string][]StartingPlayer
-
-func (se StartingEleven) PlayerCount() int {
-	count := 0
-	for position := range se {
-		for range position {
-			count++
-		}
-	}
-	return count
-}
-
-func (se StartingEleven) Score() float32 {
-	score := float32(0)
-	for _, players := range se {
-		for _, player := range players {
-			score += player.Score()
-		}
-	}
-	return score
-}
-
 type BestTeam struct {
 	Goalkeepers []StartingPlayer
 	Defenders   []StartingPlayer
@@ -129,9 +133,60 @@ func (bt *BestTeam) PlayerCount() int {
 		len(bt.Forwards)
 }
 
+// Add puts the player in the list for their position.
+func (bt *BestTeam) Add(player StartingPlayer) {
+	switch player.Player.Type.Name {
+	case "Goalkeeper":
+		bt.Goalkeepers = append(bt.Goalkeepers, player)
+	case "Defender":
+		bt.Defenders = append(bt.Defenders, player)
+	case "Midfielder":
+		bt.Midfielders = append(bt.Midfielders, player)
+	case "Forward":
+		bt.Forwards = append(bt.Forwards, player)
+	}
+}
+
+func (bt *BestTeam) Players() []StartingPlayer {
+	players := make([]StartingPlayer, 0, bt.PlayerCount())
+	players = append(players, bt.Goalkeepers...)
+	players = append(players, bt.Defenders...)
+	players = append(players, bt.Midfielders...)
+	players = append(players, bt.Forwards...)
+	return players
+}
+
 type TeamConfig struct {
-	Players   []StartingPlayer
+	// the squad's players with a fixture in the gameweek
+	Players []StartingPlayer
+	// the whole squad, including anyone without a fixture
+	Squad     []Player
 	BankValue float32
+	// what each of the squad would sell for
+	SellingPrices map[PlayerID]float32
+}
+
+// SquadValue is what the manager could spend on a new squad: the bank plus what
+// the current one would sell for.
+func (c TeamConfig) SquadValue() float32 {
+	value := c.BankValue
+	for _, player := range c.Squad {
+		value += c.SellingPrices[player.ID]
+	}
+	return roundMoney(value)
+}
+
+// SquadAtSellingPrices is the squad priced at what each player would sell for, which
+// is all that matters about the price of a player already owned.
+func (c TeamConfig) SquadAtSellingPrices() []Player {
+	squad := make([]Player, 0, len(c.Squad))
+	for _, player := range c.Squad {
+		if price, ok := c.SellingPrices[player.ID]; ok {
+			player.RawCost = price
+		}
+		squad = append(squad, player)
+	}
+	return squad
 }
 
 func main() {
@@ -139,18 +194,116 @@ func main() {
 	playerType := flag.String("type", "", "for viewing a list of top players of a type")
 	gameWeekInt := flag.Int("gameweek", 0, "for specifying the gameweek")
 	managerID := flag.Int("manager-id", 0, "for specifying your manager id")
+	teamFile := flag.String("team-file", "", "for reading your squad from a file instead of -manager-id, e.g. team.json")
 	save := flag.Bool("save", false, "for storing data")
+	fromStore := flag.Int("from-store", 0, "for using the data saved for this gameweek instead of the live data")
+	explain := flag.Bool("explain", false, "for showing how each player's score was calculated")
+	versus := flag.String("vs", "", "for comparing the -player with another player")
+	winnersOnly := flag.Bool("winners-only", false, "for only picking players whose team is expected to win")
+	simulate := flag.Int("simulate", 0, "for simulating the team's points over this many iterations")
+	target := flag.Float64("target", 60, "for the points total to beat when simulating")
+	setPiecesFile := flag.String("set-pieces", "setpieces.json", "for specifying a file of set piece taker overrides")
+	setPieceUplift := flag.Float64("set-piece-uplift", float64(scoringConfig.SetPieceUplift), "for how much being the first choice penalty taker increases a score")
+	risk := flag.Float64("risk", 0, "for how much to avoid players whose points swing, negative to favour them")
+	budget := flag.Float64("budget", defaultBudget, "for the most the whole squad can cost, in millions")
+	lock := flag.String("lock", "", "for players who must be in the team, comma separated")
+	exclude := flag.String("exclude", "", "for players who can't be in the team, comma separated")
+	clubCap := flag.String("club-cap", "", "for the most players from one club, e.g. 2 or ARS=1,LIV=2")
+	positionCap := flag.String("position-cap", "", "for the most starters in a position, e.g. FWD=2")
+	horizon := flag.Int("horizon", 3, "for how many gameweeks ahead to plan transfers")
+	freeTransfers := flag.Int("free-transfers", 1, "for how many free transfers you have")
+	maxTransfers := flag.Int("max-transfers", 2, "for the most transfers to consider making this gameweek")
+	transferOptions := flag.Int("transfer-options", 5, "for how many transfer options to list")
+	alternatives := flag.Int("alternatives", 0, "for listing this many alternatives to the best team")
+	minDifference := flag.Int("min-difference", 3, "for how many players each alternative team has to change")
+	chip := flag.String("chip", "", "for rebuilding your squad with a chip, wildcard or freehit")
+	ratings := flag.String("ratings", fplRatings, "for the fixture difficulty ratings to use, fpl or custom")
+	weeks := flag.Int("weeks", 6, "for how many gameweeks of fixtures to show")
+	window := flag.Int("window", 0, "for how many of those gameweeks to sort the fixtures by, all of them if 0")
+	view := flag.String("view", attackView, "for rating fixtures for attack or defence")
 	flag.Parse()
 
-	if *gameWeekInt == 0 {
+	command := flag.Arg(0)
+
+	if *gameWeekInt == 0 && command != "setpieces" {
 		panic("You must provide a gameweek number")
 	}
 
-	data, err := BuildData()
-	if err != nil {
+	scoringConfig.SetPieceUplift = float32(*setPieceUplift)
+	scoringConfig.RiskAversion = float32(*risk)
+
+	if *fromStore > 0 && *save {
+		fmt.Println("-save can't be used with -from-store, as it would store the snapshot back over a gameweek's own")
+		return
+	}
+
+	var data *Data
+	var err error
+	if *fromStore > 0 {
+		data, err = LoadStoredData(*fromStore)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	} else {
+		data, err = BuildData()
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if err := data.LoadSetPieceOverrides(*setPiecesFile); err != nil {
 		panic(err)
 	}
 
+	// match history is only needed to score players, and to look at one player only
+	// theirs is, unless -explain compares them with the rest of their position or
+	// everyone's is being saved. The fixture ticker doesn't score anyone.
+	partialHistory := false
+	if *fromStore == 0 && command != "setpieces" && (command != "fixtures" || *save) {
+		if command == "" && *playerName != "" && !*explain && !*save {
+			partialHistory = true
+			// when nobody matches there's no history to load and the player isn't found
+			if playerIDs := playerIDsNamed(data.Players, *playerName, *versus); len(playerIDs) > 0 {
+				data.LoadPlayerHistory(playerIDs...)
+			}
+		} else {
+			data.LoadPlayerHistory()
+		}
+	}
+
+	switch *ratings {
+	case fplRatings:
+	case customRatings:
+		fixtureRatings, err := data.RateFixtures()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		scoringConfig.Ratings = &fixtureRatings
+	default:
+		fmt.Printf("-ratings: expected %s or %s, got '%s'\n", fplRatings, customRatings, *ratings)
+		return
+	}
+
+	constraints, err := data.ParseTeamConstraints(*lock, *exclude, *clubCap, *positionCap)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if command == "setpieces" {
+		printSetPieces(data.Teams)
+		return
+	}
+
+	if command == "calibrate" {
+		if err := runCalibration(data, *gameWeekInt); err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	var gameweek *Gameweek
 	if *gameWeekInt > 0 {
 		gameweek = data.Gameweek(*gameWeekInt)
@@ -171,54 +324,104 @@ func main() {
 		}()
 	}
 
+	// loadSquad reads the squad to analyse from -team-file or fetches it for -manager-id
+	loadSquad := func() (TeamConfig, error) {
+		if *teamFile != "" {
+			return data.LoadTeamFile(*teamFile, gameweek.ID)
+		}
+		return data.RequestManagerPicks(*managerID), nil
+	}
+
+	if command == "chips" {
+		if *managerID == 0 && *teamFile == "" {
+			fmt.Println("The chips planner needs your -manager-id or -team-file")
+			return
+		}
+		// chips played can only be looked up with a manager id
+		var played []PlayedChip
+		if *managerID != 0 {
+			played = data.RequestManagerChips(*managerID)
+		}
+		config, err := loadSquad()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		schedule, err := data.planChips(config, played, gameweek.ID, *horizon, constraints)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		printChipSchedule(schedule)
+		fmt.Println()
+		return
+	}
+
+	if command == "fixtures" {
+		ticker, err := data.fixtureTicker(gameweek.ID, *weeks, *window, *view)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		printFixtureTicker(ticker)
+		fmt.Println()
+		return
+	}
+
+	if command == "swings" {
+		swings, err := data.findSwings(gameweek.ID, *weeks, *view)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		printSwings(swings)
+		fmt.Println()
+		return
+	}
+
 	previousGameweek := data.Gameweek(int(gameweek.ID) - 1)
+	var mostCaptained PlayerID
+	if previousGameweek != nil {
+		mostCaptained = previousGameweek.MostCaptainedID
+	}
 
 	// set used in case multiple fixtures in one gameweek for a team
-	likelyWinnerPlayersSet := make(map[PlayerID]StartingPlayer, 0)
+	poolPlayersSet := make(map[PlayerID]StartingPlayer, 0)
 
 	for _, fixture := range data.FixturesByGameWeek(*gameWeekInt) {
-		var likelyWinner Team
-		var opposingTeam Team
-		if fixture.HomeTeamDifficulty < fixture.AwayTeamDifficulty {
-			likelyWinner = *fixture.HomeTeam
-			opposingTeam = *fixture.AwayTeam
-		} else if fixture.HomeTeamDifficulty > fixture.AwayTeamDifficulty {
-			likelyWinner = *fixture.AwayTeam
-			opposingTeam = *fixture.HomeTeam
-		}
-		for _, player := range likelyWinner.Players {
-			if previousGameweek != nil {
-				player.MostCaptained = (previousGameweek.MostCaptainedID == player.ID)
+		sides := [][2]*Team{
+			{fixture.HomeTeam, fixture.AwayTeam},
+			{fixture.AwayTeam, fixture.HomeTeam},
+		}
+		for _, side := range sides {
+			team, opposingTeam := side[0], side[1]
+			if *winnersOnly && fixture.LikelyWinner() != team {
+				continue
 			}
-
-			// player already exists
-			likelyWinnerPlayersSet[player.ID] = StartingPlayer{
-				Player:       player,
-				Fixture:      fixture,
-				OpposingTeam: opposingTeam,
+			for _, player := range team.Players {
+				player.MostCaptained = (mostCaptained == player.ID)
+
+				// player already exists
+				poolPlayersSet[player.ID] = StartingPlayer{
+					Player:       player,
+					Fixture:      fixture,
+					OpposingTeam: *opposingTeam,
+				}
 			}
 		}
 	}
 
-	likelyWinnerPlayers := make([]StartingPlayer, 0)
-	for _, player := range likelyWinnerPlayersSet {
-		likelyWinnerPlayers = append(likelyWinnerPlayers, player)
+	poolPlayers := make([]StartingPlayer, 0)
+	for _, player := range poolPlayersSet {
+		poolPlayers = append(poolPlayers, player)
 	}
 
-	rankedStartingPlayers := rankPlayers(likelyWinnerPlayers)
+	rankedStartingPlayers := rankPlayers(poolPlayers)
 
 	if *playerName != "" {
-		var matchingPlayer StartingPlayer
-		t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
 		players := rankPlayers(data.GameweekPlayers(*gameWeekInt))
-		for _, player := range players {
-			flatString, _, _ := transform.String(t, player.Player.Name)
-			if fuzzy.Match(*playerName, flatString) || fuzzy.Match(*playerName, player.Player.Name) {
-				matchingPlayer = player
-				break
-			}
-		}
-		if matchingPlayer.Player.Name == "" {
+		matchingPlayer, ok := findPlayer(players, *playerName)
+		if !ok {
 			fmt.Printf("player '%s' not found\n", *playerName)
 			return
 		}
@@ -227,11 +430,58 @@ func main() {
 		fmt.Printf("Cost: %s\n", matchingPlayer.Player.Cost)
 		fmt.Printf("Form: %.2f\n", matchingPlayer.Player.Form)
 		fmt.Printf("Score: %.0f\n", matchingPlayer.Score())
+		fmt.Printf("Expected Points: %.1f\n", matchingPlayer.ExpectedPoints())
+		fmt.Printf("Expected Bonus: %.2f\n", matchingPlayer.ExpectedBonus())
+		if matchingPlayer.Player.SetPieces != (SetPieceOrders{}) {
+			fmt.Printf("Set Pieces: penalties %s, direct free kicks %s, corners & indirect %s\n",
+				ordinalOrNone(matchingPlayer.Player.SetPieces.Penalties),
+				ordinalOrNone(matchingPlayer.Player.SetPieces.DirectFreekicks),
+				ordinalOrNone(matchingPlayer.Player.SetPieces.CornersAndIndirectFreekicks),
+			)
+		}
 		fmt.Printf("PPG: %.2f\n", matchingPlayer.Player.PointsPerGame)
 		fmt.Printf("WPPG: %.2f\n", matchingPlayer.WeightedPointsAverage())
+		minutes := matchingPlayer.Minutes()
+		fmt.Printf("Expected Minutes: %.0f (60+ mins: %.0f%%)\n", minutes.ExpectedMinutes, minutes.SixtyPlusProbability*100)
+		fmt.Printf("Start Rate: %.0f%%, Early Subs: %.0f%%\n", minutes.StartRate*100, minutes.EarlySubRate*100)
+		if minutes.ReturningFromInjury {
+			fmt.Println("Returning from injury, minutes may be managed")
+		}
+		variance := matchingPlayer.Variance()
+		fmt.Printf("Points Spread: %.1f ± %.1f (%.1f ± %.1f when playing, plays %.0f%%)\n",
+			variance.Mean,
+			variance.StdDev,
+			variance.PlayingMean,
+			variance.PlayingStdDev,
+			variance.PlayChance*100,
+		)
+		if scoringConfig.RiskAversion != 0 {
+			fmt.Printf("Risk Adjusted Score: %.0f\n", matchingPlayer.RiskAdjustedScore())
+		}
 		fmt.Printf("Picked: %.1f%%\n", matchingPlayer.Player.PickedPercentage)
-		fmt.Printf("Overall Rank: %s, by Type: %s\n", matchingPlayer.OverallRank, matchingPlayer.TypeRank)
+		// ranks would compare the player's minutes from their history with everyone
+		// else's from their season totals
+		if !partialHistory {
+			fmt.Printf("Overall Rank: %s, by Type: %s\n", matchingPlayer.OverallRank, matchingPlayer.TypeRank)
+		}
 		fmt.Printf("Opposition: %s\n", matchingPlayer.OpposingTeam.Name)
+		fmt.Printf("Fixture Difficulty: attack %s, defence %s\n",
+			formatDifficulty(matchingPlayer.Fixture.AttackDifficulty(matchingPlayer.Player.Team.ID)),
+			formatDifficulty(matchingPlayer.Fixture.DefenceDifficulty(matchingPlayer.Player.Team.ID)),
+		)
+		result := matchingPlayer.Fixture.Result(matchingPlayer.Player.Team.ID)
+		fmt.Printf("Result: %.0f%% win, %.0f%% draw, %.0f%% loss\n", result.Win*100, result.Draw*100, result.Loss*100)
+		if *explain {
+			printScoreExplanation(explainScore(matchingPlayer, players))
+		}
+		if *versus != "" {
+			otherPlayer, ok := findPlayer(players, *versus)
+			if !ok {
+				fmt.Printf("player '%s' not found\n", *versus)
+				return
+			}
+			printScoreComparison(matchingPlayer, otherPlayer)
+		}
 		return
 	}
 
@@ -257,14 +507,26 @@ func main() {
 		return
 	}
 
-	differentials := differentialPlayers(rankedStartingPlayers)
-	bestTeam := createHighestScoringTeam(rankedStartingPlayers)
-
-	if *managerID != 0 {
+	if *managerID != 0 || *teamFile != "" {
 		gameweekPlayers := data.GameweekPlayers(*gameWeekInt)
 		gameweekPlayerSet := data.GameweekPlayerSet(GameweekID(*gameWeekInt))
 
-		config := data.RequestManagerPicks(*managerID)
+		config, err := loadSquad()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if *chip != "" {
+			chipSquad, err := data.buildChipSquad(*chip, config, gameweek.ID, *horizon, constraints)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			printChipSquad(chipSquad)
+			fmt.Println()
+			return
+		}
 
 		myGameweekPlayers := make([]StartingPlayer, 0)
 		for _, pick := range config.Players {
@@ -272,105 +534,111 @@ func main() {
 			if _, ok := gameweekPlayerSet[PlayerID(pick.Player.ID)]; !ok {
 				continue
 			}
-			myGameweekPlayers = append(myGameweekPlayers, gameweekPlayerSet[PlayerID(pick.Player.ID)])
+			startingPlayer := gameweekPlayerSet[PlayerID(pick.Player.ID)]
+			startingPlayer.Player.MostCaptained = (mostCaptained == startingPlayer.Player.ID)
+			myGameweekPlayers = append(myGameweekPlayers, startingPlayer)
 		}
 
 		myGameweekPlayers = sortStartingPlayersByScore(myGameweekPlayers)
 
-		bestTeam := createHighestScoringTeam(myGameweekPlayers)
+		// only the locked players in the squad who have a fixture can be picked, the rest
+		// are bought by the transfers below
+		squad, err := newMatchdaySquad(data.PlayerTypes, myGameweekPlayers, config.BankValue, constraints.lockedAmong(myGameweekPlayers))
+		if err != nil {
+			fmt.Printf("\nNo team can be picked from your squad for %s: %v\n\n", gameweek.Name, err)
+			return
+		}
+		bestTeam := squad.Starting
+		captaincy := recommendCaptain(bestTeam.Players(), data.FixturesByGameWeek(*gameWeekInt), mostCaptained)
 		fmt.Printf("\nWith your current players, the best team you could pick for %s is:\n", gameweek.Name)
 		headerFmt, columnFmt := tableFormat()
 		tbl := table.New("Type", "Name", "Form", "PPG", "WPPG", "Score", "Picked", "Cost", "Opponent")
 		tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
-		appendOptions := AppendOptions{withPickedPercentage: true}
+		appendOptions := AppendOptions{
+			withPickedPercentage: true,
+			captain:              captaincy.Safe.Captain.Player.Player.ID,
+			viceCaptain:          captaincy.Safe.ViceCaptain.Player.Player.ID,
+		}
 		appendToTable(tbl, bestTeam.Goalkeepers, appendOptions)
 		appendToTable(tbl, bestTeam.Defenders, appendOptions)
 		appendToTable(tbl, bestTeam.Midfielders, appendOptions)
 		appendToTable(tbl, bestTeam.Forwards, appendOptions)
 		tbl.Print()
+		fmt.Printf("\nOn the bench:\n")
+		printBench(squad, simulateAutosubs(data.PlayerTypes, squad, expectedPointsIterations))
+		printCaptaincy(captaincy)
+		fmt.Println()
+		printTableKey()
+		if *explain {
+			fmt.Println()
+			printTeamExplanation(bestTeam.Players(), gameweekPlayers)
+		}
+		if *simulate > 0 {
+			printSimulation(simulateTeam(bestTeam.Players(), *simulate, float32(*target)))
+		}
 
-		worstPlayer := myGameweekPlayers[len(myGameweekPlayers)-1]
-		cashAfterSale := worstPlayer.Player.RawCost + config.BankValue
+		// locked players who aren't in the squad have to be bought this gameweek
+		mustBuy := constraints.lockedOutside(config.SquadAtSellingPrices())
 
-		playersICanAfford := make([]StartingPlayer, 0)
-		for _, potential := range gameweekPlayers {
-			if potential.Player.RawCost <= cashAfterSale && potential.Player.Type.ID == worstPlayer.Player.Type.ID {
-				playersICanAfford = append(playersICanAfford, potential)
+		if *horizon > 0 {
+			plan := data.planTransfers(config.SquadAtSellingPrices(), config.BankValue, *freeTransfers, gameweek.ID, *horizon, constraints)
+			if len(plan.Weeks) == 0 && len(mustBuy) > 0 {
+				fmt.Printf("\nNo transfer plan can buy %s in %s.\n", playerNames(mustBuy), gameweek.Name)
 			}
+			printTransferPlan(plan)
 		}
 
-		playersICanAfford = sortStartingPlayersByScore(playersICanAfford)
-		topPick := playersICanAfford[0]
-
-		fmt.Printf(
-			"\nYou might want to consider selling %s and buying %s, who costs %s and has a score of %.0f.\n\n",
-			worstPlayer.Player.Name,
-			topPick.Player.Name,
-			topPick.Player.Cost,
-			topPick.Score(),
-		)
-
-		fmt.Printf("Type './simple-fantasy -gameweek %d -player %s' to find out more about him.\n\n", *gameWeekInt, topPick.Player.Name)
-
-		// what could 2 transfers get you?
-		secondWorstPlayer := myGameweekPlayers[len(myGameweekPlayers)-2]
-		cashAfterSale = worstPlayer.Player.RawCost + secondWorstPlayer.Player.RawCost + config.BankValue
-		scoresAndPlayers := make(map[float32][]StartingPlayer, 0)
-		sortedGameweekPlayers := sortStartingPlayersByScore(gameweekPlayers)
-		for _, potentialFirstTransfer := range gameweekPlayers {
-			if potentialFirstTransfer.Player.Type.ID != worstPlayer.Player.Type.ID && potentialFirstTransfer.Player.Type.ID != secondWorstPlayer.Player.Type.ID {
-				continue
-			}
-			cashNow := cashAfterSale - potentialFirstTransfer.Player.RawCost
-			var potentialSecondTransferType PlayerTypeID
-			if potentialFirstTransfer.Player.Type.ID == worstPlayer.Player.Type.ID {
-				potentialSecondTransferType = secondWorstPlayer.Player.Type.ID
-			} else if potentialFirstTransfer.Player.Type.ID == secondWorstPlayer.Player.Type.ID {
-				potentialSecondTransferType = worstPlayer.Player.Type.ID
-			}
-			for _, potentialSecondTransfer := range sortedGameweekPlayers {
-				if (cashNow-potentialSecondTransfer.Player.RawCost) >= 0 &&
-					potentialSecondTransfer.Player.ID != potentialFirstTransfer.Player.ID &&
-					potentialSecondTransfer.Player.Type.ID == potentialSecondTransferType {
-					combinedScore := potentialFirstTransfer.Score() + potentialSecondTransfer.Score()
-					scoresAndPlayers[combinedScore] = append(
-						scoresAndPlayers[combinedScore],
-						[]StartingPlayer{potentialFirstTransfer, potentialSecondTransfer}...,
-					)
-				}
+		options := data.searchTransfers(config.SquadAtSellingPrices(), config.BankValue, *freeTransfers, gameweek.ID, *maxTransfers, *transferOptions, constraints)
+		if len(options) == 0 {
+			if len(mustBuy) > 0 {
+				fmt.Printf("\nThere are no transfers you can make that buy %s.\n\n", playerNames(mustBuy))
+				return
 			}
+			fmt.Printf("\nThere are no transfers you can make.\n\n")
+			return
 		}
+		printTransferOptions(gameweek, options)
+		fmt.Printf("\nType './simple-fantasy -gameweek %d -player %s' to find out more about %s.\n\n", *gameWeekInt, options[0].In[0].Name, options[0].In[0].Name)
 
-		scoreKeys := make([]float32, 0)
-		for key := range scoresAndPlayers {
-			scoreKeys = append(scoreKeys, key)
-		}
-		sort.Slice(scoreKeys, func(i, j int) bool {
-			return scoreKeys[i] > scoreKeys[j]
-		})
-		bestPair := scoresAndPlayers[scoreKeys[0]]
-		if len(bestPair) > 1 {
-			formattedCash := fmt.Sprintf("£%.1fm", float32(cashAfterSale))
-			fmt.Printf(
-				"Or if you were willing to make two transfers you could sell %s and %s, using the resulting %s and buying %s and %s, who cost %s and %s with scores %.0f and %.0f.\n\n",
-				worstPlayer.Player.Name,
-				secondWorstPlayer.Player.Name,
-				formattedCash,
-				bestPair[0].Player.Name,
-				bestPair[1].Player.Name,
-				bestPair[0].Player.Cost,
-				bestPair[1].Player.Cost,
-				bestPair[0].Score(),
-				bestPair[1].Score(),
-			)
+		if *winnersOnly {
+			fmt.Printf("(Players whose team isn't expected to win have been left out.)\n\n")
 		}
 
-		fmt.Printf("(Scores may vary where team expected to draw.)\n\n")
+		return
+	}
 
+	squad, err := optimiseSquad(data.PlayerTypes, rankedStartingPlayers, StartingPlayer.RiskAdjustedScore, float32(*budget), constraints)
+	if err != nil {
+		fmt.Printf("\nNo squad can be picked for %s: %v\n\n", gameweek.Name, err)
 		return
 	}
+	differentials, err := differentialPlayers(data.PlayerTypes, rankedStartingPlayers, constraints)
+	if err != nil {
+		fmt.Printf("\nNo differentials can be picked: %v\n", err)
+	}
 
-	printOutput(bestTeam, differentials, gameweek)
+	outputOptions := OutputOptions{
+		autosubs:  simulateAutosubs(data.PlayerTypes, squad, expectedPointsIterations),
+		captaincy: recommendCaptain(squad.Starting.Players(), data.FixturesByGameWeek(*gameWeekInt), mostCaptained),
+	}
+	if *explain {
+		outputOptions.explainPopulation = data.GameweekPlayers(*gameWeekInt)
+	}
+	printOutput(squad, differentials, gameweek, outputOptions)
+
+	if *alternatives > 0 {
+		teams, err := createAlternativeTeams(data.PlayerTypes, rankedStartingPlayers, constraints, *alternatives+1, *minDifference)
+		if err != nil {
+			fmt.Printf("No alternative teams can be picked: %v\n\n", err)
+		} else {
+			printAlternativeTeams(teams, *minDifference)
+		}
+	}
+
+	if *simulate > 0 {
+		printSimulation(simulateTeam(squad.Starting.Players(), *simulate, float32(*target)))
+		fmt.Println()
+	}
 }
 
 func rankPlayers(players []StartingPlayer) []StartingPlayer {
@@ -391,76 +659,8 @@ func rankPlayers(players []StartingPlayer) []StartingPlayer {
 	return rankedPlayers
 }
 
-func createHighestScoringTeam(startingPlayers []StartingPlayer) BestTeam {
-	positionCountCombinations := [][]int{
-		{1, 3, 5, 2},
-		{1, 4, 4, 2},
-		{1, 5, 3, 2},
-		{1, 3, 4, 3},
-		{1, 4, 3, 3},
-		{1, 5, 4, 1},
-		{1, 5, 2, 3},
-	}
-	positionVariations := make(map[string]StartingEleven)
-
-	for _, combination := range positionCountCombinations {
-		key := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(combination)), "-"), "[]") // e.g. 1-3-5-2
-
-		startingEleven := StartingEleven{}
-		teamPlayerCounts := make(map[TeamID]int, 0)
-
-		// assumes players are in descending score order
-		for _, player := range startingPlayers {
-			// you can only have 3 players from one team in your selection, continue to next ranking player
-			if teamPlayerCounts[player.Player.Team.ID] >= 3 {
-				continue
-			}
-
-			position := player.Player.Type.Name
-			switch position {
-			case "Goalkeeper":
-				if len(startingEleven[position]) < combination[0] {
-					startingEleven[position] = append(startingEleven[position], player)
-					teamPlayerCounts[player.Player.Team.ID]++
-				}
-			case "Defender":
-				if len(startingEleven[position]) < combination[1] {
-					startingEleven[position] = append(startingEleven[position], player)
-					teamPlayerCounts[player.Player.Team.ID]++
-				}
-			case "Midfielder":
-				if len(startingEleven[position]) < combination[2] {
-					startingEleven[position] = append(startingEleven[position], player)
-					teamPlayerCounts[player.Player.Team.ID]++
-				}
-			case "Forward":
-				if len(startingEleven[position]) < combination[3] {
-					startingEleven[position] = append(startingEleven[position], player)
-					teamPlayerCounts[player.Player.Team.ID]++
-				}
-			}
-		}
-
-		positionVariations[key] = startingEleven
-	}
-
-	var highestScore float32
-	var highestScoringTeam StartingEleven
-	for _, startingEleven := range positionVariations {
-		seScore := startingEleven.Score()
-		if seScore > highestScore {
-			highestScore = seScore
-			highestScoringTeam = startingEleven
-		}
-	}
-
-	var bestTeam BestTeam
-	bestTeam.Goalkeepers = highestScoringTeam["Goalkeeper"]
-	bestTeam.Defenders = highestScoringTeam["Defender"]
-	bestTeam.Midfielders = highestScoringTeam["Midfielder"]
-	bestTeam.Forwards = highestScoringTeam["Forward"]
-
-	return bestTeam
+func createHighestScoringTeam(playerTypes []PlayerType, startingPlayers []StartingPlayer, constraints TeamConstraints) (BestTeam, error) {
+	return bestLineup(playerTypes, startingPlayers, constraints)
 }
 
 func tableFormat() (table.Formatter, table.Formatter) {
@@ -469,8 +669,16 @@ func tableFormat() (table.Formatter, table.Formatter) {
 	return headerFmt, columnFmt
 }
 
-func printOutput(bestTeam BestTeam, differentials BestTeam, gameweek *Gameweek) {
+type OutputOptions struct {
+	// players to compare against when explaining scores, nil for no explanations
+	explainPopulation []StartingPlayer
+	autosubs          AutosubSummary
+	captaincy         Captaincy
+}
+
+func printOutput(squad Squad, differentials BestTeam, gameweek *Gameweek, options OutputOptions) {
 	headerFmt, columnFmt := tableFormat()
+	bestTeam := squad.Starting
 
 	tbl := table.New("Type", "Name", "Form", "PPG", "WPPG", "Score", "Picked", "Rank (Type)", "Cost", "Opponent")
 	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
@@ -479,12 +687,26 @@ func printOutput(bestTeam BestTeam, differentials BestTeam, gameweek *Gameweek)
 	} else {
 		fmt.Printf("\nThe best team you can play in %s (deadline %s) is: \n", gameweek.Name, gameweek.Deadline)
 	}
-	appendOptions := AppendOptions{withPickedPercentage: true, withRank: true}
+	appendOptions := AppendOptions{
+		withPickedPercentage: true,
+		withRank:             true,
+		captain:              options.captaincy.Safe.Captain.Player.Player.ID,
+		viceCaptain:          options.captaincy.Safe.ViceCaptain.Player.Player.ID,
+	}
 	appendToTable(tbl, bestTeam.Goalkeepers, appendOptions)
 	appendToTable(tbl, bestTeam.Defenders, appendOptions)
 	appendToTable(tbl, bestTeam.Midfielders, appendOptions)
 	appendToTable(tbl, bestTeam.Forwards, appendOptions)
 	tbl.Print()
+	if options.explainPopulation != nil {
+		fmt.Println()
+		printTeamExplanation(bestTeam.Players(), options.explainPopulation)
+	}
+
+	fmt.Printf("\nOn the bench:\n")
+	printBench(squad, options.autosubs)
+	fmt.Printf("\nThe squad costs £%.1fm, leaving £%.1fm in the bank.\n", squad.Cost, squad.Bank)
+	printCaptaincy(options.captaincy)
 
 	fmt.Printf("\nDifferentials:\n")
 	differentialsTbl := table.New("Type", "Name", "Form", "PPG", "WPPG", "Score", "Picked", "Rank (Type)", "Cost", "Opponent")
@@ -497,6 +719,10 @@ func printOutput(bestTeam BestTeam, differentials BestTeam, gameweek *Gameweek)
 	appendToTable(differentialsTbl, differentials.Midfielders, appendOptions)
 	appendToTable(differentialsTbl, differentials.Forwards, appendOptions)
 	differentialsTbl.Print()
+	if options.explainPopulation != nil {
+		fmt.Println()
+		printTeamExplanation(differentials.Players(), options.explainPopulation)
+	}
 
 	fmt.Println()
 
@@ -517,22 +743,35 @@ func printOutput(bestTeam BestTeam, differentials BestTeam, gameweek *Gameweek)
 
 	fmt.Println()
 
-	fmt.Println("(PPG = Points Per Game, WPPG = Weighted Points Per Game (by match difficulty))")
+	printTableKey()
 
 	fmt.Println()
 }
 
+func printTableKey() {
+	fmt.Println("(PPG = Points Per Game, WPPG = Weighted Points Per Game (by match difficulty))")
+	fmt.Println("((C) = captain, (V) = vice captain, (MC) = most captained last gameweek)")
+}
+
 type AppendOptions struct {
 	withPickedPercentage bool
 	withRank             bool
+	captain              PlayerID
+	viceCaptain          PlayerID
 }
 
 func appendToTable(tbl table.Table, fixtureWinners []StartingPlayer, options AppendOptions) {
 	for _, fixtureWinner := range fixtureWinners {
 		playerName := fixtureWinner.Player.Name
 
-		if fixtureWinner.Player.MostCaptained {
+		switch fixtureWinner.Player.ID {
+		case options.captain:
 			playerName += " (C)"
+		case options.viceCaptain:
+			playerName += " (V)"
+		}
+		if fixtureWinner.Player.MostCaptained {
+			playerName += " (MC)"
 		}
 
 		row := []interface{}{
@@ -570,14 +809,57 @@ func sortStartingPlayersByScore(startingPlayers []StartingPlayer) []StartingPlay
 	return newSlice
 }
 
-func differentialPlayers(startingPlayers []StartingPlayer) BestTeam {
+func differentialPlayers(playerTypes []PlayerType, startingPlayers []StartingPlayer, constraints TeamConstraints) (BestTeam, error) {
 	players := make([]StartingPlayer, 0)
 	for _, startingPlayer := range startingPlayers {
-		if startingPlayer.Player.PickedPercentage < 15 {
+		// locked players are wanted however popular they are
+		if startingPlayer.Player.PickedPercentage < 15 || constraints.isLocked(startingPlayer) {
 			players = append(players, startingPlayer)
 		}
 	}
-	return createHighestScoringTeam(players)
+	return createHighestScoringTeam(playerTypes, players, constraints)
+}
+
+// findPlayer returns the highest ranked player whose name fuzzily matches, ignoring accents.
+func findPlayer(players []StartingPlayer, name string) (StartingPlayer, bool) {
+	for _, player := range players {
+		if matchesName(player.Player.Name, name) {
+			return player, true
+		}
+	}
+	return StartingPlayer{}, false
+}
+
+// playerIDsNamed is every player whose name matches one of the searches.
+func playerIDsNamed(players []Player, searches ...string) []PlayerID {
+	ids := make([]PlayerID, 0)
+	for _, player := range players {
+		for _, search := range searches {
+			if search != "" && matchesName(player.Name, search) {
+				ids = append(ids, player.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func matchesName(playerName string, search string) bool {
+	return fuzzy.Match(search, flattenName(playerName)) || fuzzy.Match(search, playerName)
+}
+
+// flattenName strips the accents from a name, e.g. "Guéhi" to "Guehi".
+func flattenName(name string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	flatString, _, _ := transform.String(t, name)
+	return flatString
+}
+
+func ordinalOrNone(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return ordinalNumber(n)
 }
 
 func ordinalNumber(n int) string {
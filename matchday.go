@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rodaine/table"
+)
+
+// newMatchdaySquad lays out a squad for the deadline: the best eleven it can field
+// and everyone else on the bench.
+func newMatchdaySquad(playerTypes []PlayerType, players []StartingPlayer, bank float32, constraints TeamConstraints) (Squad, error) {
+	starting, err := bestLineup(playerTypes, players, constraints)
+	if err != nil {
+		return Squad{}, err
+	}
+	squad := Squad{
+		Starting: starting,
+		Bank:     bank,
+	}
+
+	starters := make(map[PlayerID]bool, 0)
+	for _, player := range squad.Starting.Players() {
+		starters[player.Player.ID] = true
+	}
+
+	bench := make([]StartingPlayer, 0)
+	for _, player := range players {
+		squad.Cost += player.Player.RawCost
+		if starters[player.Player.ID] {
+			squad.Value += player.Score()
+			continue
+		}
+		squad.Value += player.Score() * squadBenchWeight
+		bench = append(bench, player)
+	}
+	squad.Bench = orderBench(bench)
+
+	return squad, nil
+}
+
+// orderBench puts the backup goalkeeper first, as FPL does, then the outfield
+// players in the order they should come on, most expected points first.
+func orderBench(bench []StartingPlayer) []StartingPlayer {
+	ordered := make([]StartingPlayer, 0, len(bench))
+	outfield := make([]StartingPlayer, 0, len(bench))
+	for _, player := range bench {
+		if player.Player.Type.Name == "Goalkeeper" {
+			ordered = append(ordered, player)
+		} else {
+			outfield = append(outfield, player)
+		}
+	}
+	sort.SliceStable(outfield, func(i, j int) bool {
+		return outfield[i].ExpectedPoints() > outfield[j].ExpectedPoints()
+	})
+	return append(ordered, outfield...)
+}
+
+// AutosubSummary is what the bench is likely to add through automatic substitutions.
+type AutosubSummary struct {
+	Iterations int
+	// mean points from the starting eleven on their own
+	StartingPoints float32
+	// mean points added by substitutes coming on
+	BenchPoints float32
+	// how often each bench player comes on
+	CameOn map[PlayerID]float32
+	// each bench player's mean points from coming on, 0 when they stay on the bench
+	Contribution map[PlayerID]float32
+}
+
+// simulateAutosubs plays out the squad's fixtures and makes FPL's automatic
+// substitutions: a starter who doesn't play is replaced by the first player on the
+// bench who did, as long as the formation stays legal, and only a goalkeeper can
+// replace a goalkeeper.
+func simulateAutosubs(playerTypes []PlayerType, squad Squad, iterations int) AutosubSummary {
+	summary := AutosubSummary{
+		Iterations:   iterations,
+		CameOn:       make(map[PlayerID]float32, 0),
+		Contribution: make(map[PlayerID]float32, 0),
+	}
+	if iterations == 0 {
+		return summary
+	}
+
+	limits := make(map[PlayerTypeID]PlayerType, 0)
+	for _, playerType := range playerTypes {
+		limits[playerType.ID] = playerType
+	}
+
+	starters := squad.Starting.Players()
+	lineup := make(map[PlayerTypeID]int, 0)
+	for _, player := range starters {
+		lineup[player.Player.Type.ID]++
+	}
+
+	simulations := make(map[PlayerID]fixtureSimulation, 0)
+	for _, players := range [][]StartingPlayer{starters, squad.Bench} {
+		for _, player := range players {
+			simulations[player.Player.ID] = simulateFixture(player.Fixture, iterations)
+		}
+	}
+	played := func(player StartingPlayer, iteration int) bool {
+		return simulations[player.Player.ID].played[player.Player.ID][iteration]
+	}
+	points := func(player StartingPlayer, iteration int) float32 {
+		return simulations[player.Player.ID].points[player.Player.ID][iteration]
+	}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		counts := make(map[PlayerTypeID]int, len(lineup))
+		for typeID, count := range lineup {
+			counts[typeID] = count
+		}
+		used := make([]bool, len(squad.Bench))
+
+		for _, starter := range starters {
+			if played(starter, iteration) {
+				summary.StartingPoints += points(starter, iteration)
+				continue
+			}
+
+			out := starter.Player.Type
+			for b, sub := range squad.Bench {
+				in := sub.Player.Type
+				if used[b] || !played(sub, iteration) {
+					continue
+				}
+				if (out.Name == "Goalkeeper") != (in.Name == "Goalkeeper") {
+					continue
+				}
+				if in.ID != out.ID && (counts[out.ID]-1 < limits[out.ID].TeamMinPlayCount || counts[in.ID]+1 > limits[in.ID].TeamMaxPlayCount) {
+					continue
+				}
+
+				used[b] = true
+				counts[out.ID]--
+				counts[in.ID]++
+				summary.BenchPoints += points(sub, iteration)
+				summary.CameOn[sub.Player.ID]++
+				summary.Contribution[sub.Player.ID] += points(sub, iteration)
+				break
+			}
+		}
+	}
+
+	summary.StartingPoints /= float32(iterations)
+	summary.BenchPoints /= float32(iterations)
+	for playerID := range summary.CameOn {
+		summary.CameOn[playerID] /= float32(iterations)
+		summary.Contribution[playerID] /= float32(iterations)
+	}
+
+	return summary
+}
+
+func printBench(squad Squad, autosubs AutosubSummary) {
+	headerFmt, columnFmt := tableFormat()
+	tbl := table.New("Order", "Type", "Name", "xP", "Comes On", "Adds", "Cost", "Opponent")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	outfield := 0
+	for _, player := range squad.Bench {
+		order := "GK"
+		if player.Player.Type.Name != "Goalkeeper" {
+			outfield++
+			order = fmt.Sprint(outfield)
+		}
+		tbl.AddRow(
+			order,
+			player.Player.Type.ShortName,
+			player.Player.Name,
+			fmt.Sprintf("%.1f", player.ExpectedPoints()),
+			fmt.Sprintf("%.0f%%", autosubs.CameOn[player.Player.ID]*100),
+			fmt.Sprintf("%.2f", autosubs.Contribution[player.Player.ID]),
+			player.Player.Cost,
+			player.OpposingTeam.Name,
+		)
+	}
+	tbl.Print()
+	fmt.Printf(
+		"\nAutomatic substitutions should add %.1f points to the starting eleven's %.1f (over %d simulations).\n",
+		autosubs.BenchPoints,
+		autosubs.StartingPoints,
+		autosubs.Iterations,
+	)
+}
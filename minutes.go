@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// how many of a player's most recent team matches the minutes model looks at
+	minutesWindow = 8
+	// each older match counts this much less than the one after it
+	minutesRecencyDecay = 0.85
+	// a run of at least this many unused matches before a return is treated as an injury
+	injuryAbsenceRun = 3
+	// appearances after an injury during which a player's minutes are assumed to be managed
+	injuryReturnMatches = 2
+	// minutes when starting are scaled by this much while a player is being eased back in
+	injuryReturnMinutesFactor = 0.85
+)
+
+// MinutesPrediction is how long a player is expected to be on the pitch in a fixture.
+type MinutesPrediction struct {
+	StartRate              float32
+	AverageStartingMinutes float32
+	AverageSubMinutes      float32
+	SubAppearanceRate      float32
+	EarlySubRate           float32
+	ChanceOfPlaying        float32
+	// expected minutes assuming the player is available, i.e. ignoring ChanceOfPlaying
+	AvailableMinutes     float32
+	ExpectedMinutes      float32
+	SixtyPlusProbability float32
+	ReturningFromInjury  bool
+}
+
+func (sp StartingPlayer) Minutes() MinutesPrediction {
+	cacheKey := fmt.Sprintf("minutes_player_%d_%d", sp.Player.ID, sp.Fixture.ID)
+	if val, exists := cache[cacheKey]; exists {
+		return val.(MinutesPrediction)
+	}
+
+	chanceOfPlaying, ok := sp.Player.ChanceOfPlaying[sp.Fixture.Gameweek.ID]
+	if !ok {
+		chanceOfPlaying = 1
+	}
+
+	var prediction MinutesPrediction
+	if len(sp.Player.History) > 0 {
+		prediction = predictMinutesFromHistory(sp.Player.History)
+	} else {
+		prediction = predictMinutesFromSeason(sp.Player)
+	}
+
+	prediction.ChanceOfPlaying = chanceOfPlaying
+	prediction.AvailableMinutes = prediction.StartRate*prediction.AverageStartingMinutes +
+		(1-prediction.StartRate)*prediction.SubAppearanceRate*prediction.AverageSubMinutes
+	prediction.ExpectedMinutes = chanceOfPlaying * prediction.AvailableMinutes
+	prediction.SixtyPlusProbability = chanceOfPlaying * prediction.StartRate * (1 - prediction.EarlySubRate)
+
+	cache[cacheKey] = prediction
+
+	return prediction
+}
+
+// recentMatches returns the player's finished matches, most recent first.
+func recentMatches(history map[FixtureID]PlayerFixture) []PlayerFixture {
+	matches := make([]PlayerFixture, 0, len(history))
+	for _, match := range history {
+		matches = append(matches, match)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Kickoff.Equal(matches[j].Kickoff) {
+			return matches[i].FixtureID > matches[j].FixtureID
+		}
+		return matches[i].Kickoff.After(matches[j].Kickoff)
+	})
+	return matches
+}
+
+func predictMinutesFromHistory(history map[FixtureID]PlayerFixture) MinutesPrediction {
+	matches := recentMatches(history)
+
+	// a player who has come back after several unused matches was most likely injured,
+	// so those matches say nothing about whether they'll be picked now
+	var prediction MinutesPrediction
+	appearances := 0
+	for i, match := range matches {
+		if match.Played {
+			appearances++
+			continue
+		}
+		if appearances == 0 || appearances > injuryReturnMatches {
+			break
+		}
+		absenceRun := 0
+		for _, absent := range matches[i:] {
+			if absent.Played {
+				break
+			}
+			absenceRun++
+		}
+		if absenceRun >= injuryAbsenceRun {
+			prediction.ReturningFromInjury = true
+			matches = append(matches[:i:i], matches[i+absenceRun:]...)
+		}
+		break
+	}
+
+	if len(matches) > minutesWindow {
+		matches = matches[:minutesWindow]
+	}
+
+	var (
+		totalWeight, startWeight, startMinutes, earlySubWeight float32
+		nonStartWeight, subWeight, subMinutes                  float32
+	)
+	weight := float32(1)
+	for _, match := range matches {
+		totalWeight += weight
+		if match.Started {
+			startWeight += weight
+			startMinutes += weight * float32(match.Minutes)
+			if match.Minutes < 60 {
+				earlySubWeight += weight
+			}
+		} else {
+			nonStartWeight += weight
+			if match.Played {
+				subWeight += weight
+				subMinutes += weight * float32(match.Minutes)
+			}
+		}
+		weight *= minutesRecencyDecay
+	}
+
+	if totalWeight > 0 {
+		prediction.StartRate = startWeight / totalWeight
+	}
+	if startWeight > 0 {
+		prediction.AverageStartingMinutes = startMinutes / startWeight
+		prediction.EarlySubRate = earlySubWeight / startWeight
+	}
+	if nonStartWeight > 0 {
+		prediction.SubAppearanceRate = subWeight / nonStartWeight
+	}
+	if subWeight > 0 {
+		prediction.AverageSubMinutes = subMinutes / subWeight
+	}
+
+	if prediction.ReturningFromInjury {
+		prediction.AverageStartingMinutes *= injuryReturnMinutesFactor
+		if prediction.AverageStartingMinutes < 60 {
+			prediction.EarlySubRate = 1
+		}
+	}
+
+	return prediction
+}
+
+// predictMinutesFromSeason is the fallback for players without any match history,
+// using the season totals against the number of matches the team has finished.
+func predictMinutesFromSeason(player Player) MinutesPrediction {
+	var prediction MinutesPrediction
+
+	teamMatches := 0
+	for _, fixture := range player.Team.Fixtures {
+		if fixture.Gameweek.Finished {
+			teamMatches++
+		}
+	}
+	if teamMatches == 0 || player.Stats.Starts == 0 {
+		return prediction
+	}
+
+	prediction.StartRate = float32(player.Stats.Starts) / float32(teamMatches)
+	if prediction.StartRate > 1 {
+		prediction.StartRate = 1
+	}
+	prediction.AverageStartingMinutes = float32(player.Stats.Minutes) / float32(player.Stats.Starts)
+	if prediction.AverageStartingMinutes > 90 {
+		prediction.AverageStartingMinutes = 90
+	}
+	if prediction.AverageStartingMinutes < 60 {
+		prediction.EarlySubRate = 1
+	}
+
+	return prediction
+}
@@ -0,0 +1,712 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	defaultBudget = 100.0
+	// how much a point on the bench is worth compared to one in the starting eleven
+	squadBenchWeight   = 0.1
+	maxPlayersPerClub  = 3
+	startingElevenSize = 11
+)
+
+// formation is how many players start in each position.
+type formation map[PlayerTypeID]int
+
+// formations lists every starting eleven each position's minimum and maximum allows,
+// fewest players at the earliest positions first.
+func formations(playerTypes []PlayerType) []formation {
+	types := make([]PlayerType, len(playerTypes))
+	copy(types, playerTypes)
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].ID < types[j].ID
+	})
+
+	found := make([]formation, 0)
+	var fill func(t int, counts formation, players int)
+	fill = func(t int, counts formation, players int) {
+		if t == len(types) {
+			if players == startingElevenSize {
+				f := make(formation, len(counts))
+				for typeID, count := range counts {
+					f[typeID] = count
+				}
+				found = append(found, f)
+			}
+			return
+		}
+		for count := types[t].TeamMinPlayCount; count <= types[t].TeamMaxPlayCount; count++ {
+			if players+count > startingElevenSize {
+				break
+			}
+			counts[types[t].ID] = count
+			fill(t+1, counts, players+count)
+		}
+		delete(counts, types[t].ID)
+	}
+	fill(0, formation{}, 0)
+
+	return found
+}
+
+// selectionProblem is picking players into each position to maximise their value.
+// Within a position the best player takes the first slot, so slots are weighted
+// from most to least important (e.g. starters then bench).
+type selectionProblem struct {
+	candidates []StartingPlayer
+	value      func(StartingPlayer) float32
+	slots      map[PlayerTypeID][]float32
+	// budget of 0 or less means cost doesn't matter
+	budget      float32
+	constraints TeamConstraints
+	// partial fills as many slots as there are players for, instead of giving up
+	partial bool
+	// earlier selections this one has to differ from by at least minDifference players
+	distinctFrom  []map[PlayerID]bool
+	minDifference int
+}
+
+type selection struct {
+	players []StartingPlayer
+	value   float64
+	cost    float32
+	// what the search maximised, including the locked players' bonus
+	objective float64
+}
+
+type selectionCandidate struct {
+	player StartingPlayer
+	// value is what the search maximises, which is score plus a bonus for locked
+	// players big enough that they're always picked, and first in their position
+	value float64
+	score float64
+	cost  float64
+	club  TeamID
+	// club's position in the search's club counts
+	clubIndex int
+	locked    bool
+}
+
+type selectionGroup struct {
+	weights    []float64
+	candidates []selectionCandidate
+	// minCost[i][r] is the cheapest way to pick r players from candidates[i:]
+	minCost [][]float64
+	// relaxed[w][i][r] is the most r players from candidates[i:] could add in slots
+	// of the w'th distinct weight, once their cost is priced in
+	relaxed [][][]float64
+	levels  []selectionLevel
+	// index of the last locked candidate, -1 for none
+	lastLocked int
+}
+
+// selectionLevel is a run of slots in a group sharing the same weight.
+type selectionLevel struct {
+	weight float64
+	from   int
+	to     int
+}
+
+// solve finds the highest value selection by branch and bound. Anything not
+// beating minValue is ignored, which lets callers share a best-so-far between
+// several problems (pass negative infinity for no minimum).
+func (p selectionProblem) solve(minValue float64) (selection, bool) {
+	groups := p.groups()
+	for _, group := range groups {
+		if len(group.candidates) < len(group.weights) {
+			return selection{}, false
+		}
+	}
+
+	// best value still available from each group onwards, ignoring budget and clubs
+	groupBest := make([]float64, len(groups)+1)
+	groupMinCost := make([]float64, len(groups)+1)
+	for g := len(groups) - 1; g >= 0; g-- {
+		var best float64
+		for j, weight := range groups[g].weights {
+			best += weight * groups[g].candidates[j].value
+		}
+		groupBest[g] = groupBest[g+1] + best
+		groupMinCost[g] = groupMinCost[g+1] + groups[g].minCost[0][len(groups[g].weights)]
+	}
+
+	search := selectionSearch{
+		problem:      p,
+		groups:       groups,
+		groupBest:    groupBest,
+		groupMinCost: groupMinCost,
+		bestValue:    minValue,
+	}
+
+	if p.budget > 0 {
+		if groupMinCost[0] > float64(p.budget) {
+			return selection{}, false
+		}
+		search.price = budgetPrice(groups, float64(p.budget))
+		search.groupRelaxed = make([]float64, len(groups)+1)
+		for g := len(groups) - 1; g >= 0; g-- {
+			groups[g].relaxed = make([][][]float64, len(groups[g].levels))
+			for l, level := range groups[g].levels {
+				groups[g].relaxed[l] = suffixBest(groups[g].pricedValues(level.weight, search.price), level.to-level.from)
+			}
+			search.groupRelaxed[g] = search.groupRelaxed[g+1] + groups[g].relaxedBound(0, 0)
+		}
+	}
+
+	// clubs are counted in slices rather than maps as this is the hot path
+	clubIndexes := make(map[TeamID]int, 0)
+	for g := range groups {
+		for i, candidate := range groups[g].candidates {
+			index, ok := clubIndexes[candidate.club]
+			if !ok {
+				index = len(clubIndexes)
+				clubIndexes[candidate.club] = index
+				search.clubCaps = append(search.clubCaps, p.constraints.clubCap(candidate.club))
+			}
+			groups[g].candidates[i].clubIndex = index
+		}
+	}
+	search.clubCounts = make([]int, len(clubIndexes))
+
+	if len(p.distinctFrom) > 0 {
+		slots := 0
+		for _, group := range groups {
+			slots += len(group.weights)
+		}
+		search.maxOverlap = slots - p.minDifference
+		search.overlaps = make([]int, len(p.distinctFrom))
+	}
+
+	search.next(0, 0, 0, 0, 0)
+
+	if search.best == nil {
+		return selection{}, false
+	}
+
+	result := selection{objective: search.bestValue}
+	picked := 0
+	for _, group := range groups {
+		for _, weight := range group.weights {
+			candidate := search.best[picked]
+			result.players = append(result.players, candidate.player)
+			result.cost += float32(candidate.cost)
+			result.value += weight * candidate.score
+			picked++
+		}
+	}
+	return result, true
+}
+
+// groups splits the candidates by position, best first, dropping any player that
+// can't be in the best selection because enough cheaper, better players exist.
+func (p selectionProblem) groups() []selectionGroup {
+	byType := make(map[PlayerTypeID][]selectionCandidate, 0)
+	seen := make(map[PlayerID]int, 0)
+	for _, player := range p.candidates {
+		if _, ok := p.slots[player.Player.Type.ID]; !ok || p.constraints.isExcluded(player) {
+			continue
+		}
+		candidate := selectionCandidate{
+			player: player,
+			score:  float64(p.value(player)),
+			cost:   float64(player.Player.RawCost),
+			club:   player.Player.Team.ID,
+			locked: p.constraints.isLocked(player),
+		}
+		// a player with more than one fixture is only picked once, for their best one
+		if i, ok := seen[player.Player.ID]; ok {
+			existing := byType[player.Player.Type.ID][i]
+			if candidate.score > existing.score {
+				byType[player.Player.Type.ID][i] = candidate
+			}
+			continue
+		}
+		seen[player.Player.ID] = len(byType[player.Player.Type.ID])
+		byType[player.Player.Type.ID] = append(byType[player.Player.Type.ID], candidate)
+	}
+
+	// more than every player put together, even on the bench
+	var total float64
+	for _, candidates := range byType {
+		for _, candidate := range candidates {
+			total += math.Abs(candidate.score)
+		}
+	}
+	smallestWeight := float32(1)
+	for _, weights := range p.slots {
+		for _, weight := range weights {
+			if weight > 0 && weight < smallestWeight {
+				smallestWeight = weight
+			}
+		}
+	}
+	lockBonus := (total + 1) / float64(smallestWeight)
+	for _, candidates := range byType {
+		for i := range candidates {
+			candidates[i].value = candidates[i].score
+			if candidates[i].locked {
+				candidates[i].value += lockBonus
+			}
+		}
+	}
+
+	typeIDs := make([]PlayerTypeID, 0, len(p.slots))
+	totalSlots := 0
+	for typeID, weights := range p.slots {
+		typeIDs = append(typeIDs, typeID)
+		totalSlots += len(weights)
+	}
+	sort.Slice(typeIDs, func(i, j int) bool {
+		return typeIDs[i] < typeIDs[j]
+	})
+
+	groups := make([]selectionGroup, 0, len(typeIDs))
+	for _, typeID := range typeIDs {
+		candidates := byType[typeID]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].value != candidates[j].value {
+				return candidates[i].value > candidates[j].value
+			}
+			if candidates[i].cost != candidates[j].cost {
+				return candidates[i].cost < candidates[j].cost
+			}
+			return candidates[i].player.Player.ID < candidates[j].player.Player.ID
+		})
+
+		slots := p.slots[typeID]
+		if p.partial && len(candidates) < len(slots) {
+			slots = slots[:len(candidates)]
+		}
+
+		group := selectionGroup{}
+		for j, weight := range slots {
+			group.weights = append(group.weights, float64(weight))
+			if j > 0 && slots[j-1] == weight {
+				group.levels[len(group.levels)-1].to++
+				continue
+			}
+			group.levels = append(group.levels, selectionLevel{weight: float64(weight), from: j, to: j + 1})
+		}
+
+		// a better, cheaper player might be one too many in common with an earlier
+		// selection, so nobody's dominated when selections have to differ
+		if p.budget > 0 && len(p.distinctFrom) == 0 {
+			candidates = p.removeDominated(candidates, len(group.weights), totalSlots)
+		}
+		group.candidates = candidates
+		group.lastLocked = -1
+		for i, candidate := range candidates {
+			if candidate.locked {
+				group.lastLocked = i
+			}
+		}
+
+		costs := make([]float64, len(candidates))
+		for i, candidate := range candidates {
+			costs[i] = -candidate.cost
+		}
+		group.minCost = suffixBest(costs, len(group.weights))
+		for i := range group.minCost {
+			for r := range group.minCost[i] {
+				group.minCost[i][r] = -group.minCost[i][r]
+			}
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// removeDominated drops players for whom there are enough players at least as good
+// and no more expensive that one of them could always be swapped in instead, even
+// after the other slots are filled and the most crowded clubs are full. Locked
+// players are always kept.
+func (p selectionProblem) removeDominated(candidates []selectionCandidate, slots int, totalSlots int) []selectionCandidate {
+	fullClubs := totalSlots
+	if smallest := p.constraints.smallestClubCap(); smallest > 0 {
+		fullClubs = (totalSlots - 1) / smallest
+	}
+
+	kept := make([]selectionCandidate, 0, len(candidates))
+	for i, candidate := range candidates {
+		// candidates are sorted so only earlier ones can be at least as good
+		dominators := 0
+		dominatorsByClub := make(map[TeamID]int, 0)
+		for _, other := range candidates[:i] {
+			if other.cost <= candidate.cost {
+				dominators++
+				if other.club != candidate.club {
+					dominatorsByClub[other.club]++
+				}
+			}
+		}
+
+		clubCounts := make([]int, 0, len(dominatorsByClub))
+		for _, count := range dominatorsByClub {
+			clubCounts = append(clubCounts, count)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(clubCounts)))
+		blocked := slots - 1
+		for c := 0; c < fullClubs && c < len(clubCounts); c++ {
+			blocked += clubCounts[c]
+		}
+
+		if candidate.locked || dominators <= blocked {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
+
+// pricedValues is what each candidate is worth in a slot of the given weight, less
+// their cost at the given price per million.
+func (g selectionGroup) pricedValues(weight float64, price float64) []float64 {
+	values := make([]float64, len(g.candidates))
+	for i, candidate := range g.candidates {
+		values[i] = weight*candidate.value - price*candidate.cost
+	}
+	return values
+}
+
+// relaxedBound is the most the group's slots from count onwards could add when
+// filled from candidates[i:], with each slot free to take any candidate.
+func (g selectionGroup) relaxedBound(i int, count int) float64 {
+	var bound float64
+	for l, level := range g.levels {
+		from := level.from
+		if count > from {
+			from = count
+		}
+		if from < level.to {
+			bound += g.relaxed[l][i][level.to-from]
+		}
+	}
+	return bound
+}
+
+// budgetPrice finds the price per million that gives the tightest bound when cost
+// is traded off against value instead of being capped by the budget.
+func budgetPrice(groups []selectionGroup, budget float64) float64 {
+	rootBound := func(price float64) float64 {
+		bound := price * budget
+		for _, group := range groups {
+			for _, level := range group.levels {
+				values := group.pricedValues(level.weight, price)
+				sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+				for _, value := range values[:level.to-level.from] {
+					bound += value
+				}
+			}
+		}
+		return bound
+	}
+
+	var high float64
+	for _, group := range groups {
+		for _, candidate := range group.candidates {
+			if candidate.cost > 0 && candidate.value/candidate.cost > high {
+				high = candidate.value / candidate.cost
+			}
+		}
+	}
+
+	// the bound is convex in the price
+	low := 0.0
+	for i := 0; i < 60; i++ {
+		a := low + (high-low)/3
+		b := high - (high-low)/3
+		if rootBound(a) < rootBound(b) {
+			high = b
+		} else {
+			low = a
+		}
+	}
+	return (low + high) / 2
+}
+
+// suffixBest[i][r] is the sum of the r largest values from values[i:], or negative
+// infinity when there are fewer than r.
+func suffixBest(values []float64, slots int) [][]float64 {
+	best := make([][]float64, len(values)+1)
+	largest := make([]float64, 0, slots)
+	for i := len(values); i >= 0; i-- {
+		if i < len(values) {
+			value := values[i]
+			at := sort.Search(len(largest), func(j int) bool { return largest[j] < value })
+			if at < slots {
+				if len(largest) < slots {
+					largest = append(largest, 0)
+				}
+				copy(largest[at+1:], largest[at:])
+				largest[at] = value
+			}
+		}
+		best[i] = make([]float64, slots+1)
+		var total float64
+		for r := 1; r <= slots; r++ {
+			if r > len(largest) {
+				best[i][r] = math.Inf(-1)
+				continue
+			}
+			total += largest[r-1]
+			best[i][r] = total
+		}
+	}
+	return best
+}
+
+type selectionSearch struct {
+	problem      selectionProblem
+	groups       []selectionGroup
+	groupBest    []float64
+	groupMinCost []float64
+	// price and groupRelaxed bound what's left once the budget is priced in
+	price        float64
+	groupRelaxed []float64
+	picked       []selectionCandidate
+	clubCounts   []int
+	clubCaps     []int
+	best         []selectionCandidate
+	bestValue    float64
+	// players in common with each of the problem's distinctFrom selections
+	overlaps   []int
+	maxOverlap int
+}
+
+// next picks the remaining players of group g from index i onwards, having
+// already picked count players in that group.
+func (s *selectionSearch) next(g int, i int, count int, value float64, cost float64) {
+	if g == len(s.groups) {
+		if value > s.bestValue {
+			s.best = append([]selectionCandidate(nil), s.picked...)
+			s.bestValue = value
+		}
+		return
+	}
+
+	group := s.groups[g]
+	if count == len(group.weights) {
+		// every locked player has to have been picked
+		if i <= group.lastLocked {
+			return
+		}
+		s.next(g+1, 0, 0, value, cost)
+		return
+	}
+
+	budget := float64(s.problem.budget)
+	remaining := len(group.weights) - count
+	for k := i; k <= len(group.candidates)-remaining; k++ {
+		// the best this branch could do: the next players in order for this group,
+		// then the best of every later group
+		bound := value + s.groupBest[g+1]
+		for j := 0; j < remaining; j++ {
+			bound += group.weights[count+j] * group.candidates[k+j].value
+		}
+		if budget > 0 {
+			// or with the money left over priced in, if that's lower
+			relaxed := value + s.price*(budget-cost) + group.relaxedBound(k, count) + s.groupRelaxed[g+1]
+			if relaxed < bound {
+				bound = relaxed
+			}
+		}
+		if bound <= s.bestValue {
+			// later candidates are no better, so neither are their branches
+			return
+		}
+		// and they can't be any cheaper either
+		if budget > 0 && cost+group.minCost[k][remaining]+s.groupMinCost[g+1] > budget {
+			return
+		}
+
+		candidate := group.candidates[k]
+		affordable := budget <= 0 || cost+candidate.cost+group.minCost[k+1][remaining-1]+s.groupMinCost[g+1] <= budget
+		if affordable && s.clubCounts[candidate.clubIndex] < s.clubCaps[candidate.clubIndex] && s.distinct(candidate) {
+			s.picked = append(s.picked, candidate)
+			s.clubCounts[candidate.clubIndex]++
+			s.overlap(candidate, 1)
+			s.next(g, k+1, count+1, value+group.weights[count]*candidate.value, cost+candidate.cost)
+			s.overlap(candidate, -1)
+			s.clubCounts[candidate.clubIndex]--
+			s.picked = s.picked[:len(s.picked)-1]
+		}
+
+		// a locked player can't be passed over
+		if candidate.locked {
+			return
+		}
+	}
+}
+
+// distinct is whether picking the candidate still leaves the selection different
+// enough from the earlier ones.
+func (s *selectionSearch) distinct(candidate selectionCandidate) bool {
+	for t, players := range s.problem.distinctFrom {
+		if players[candidate.player.Player.ID] && s.overlaps[t] >= s.maxOverlap {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *selectionSearch) overlap(candidate selectionCandidate, change int) {
+	for t, players := range s.problem.distinctFrom {
+		if players[candidate.player.Player.ID] {
+			s.overlaps[t] += change
+		}
+	}
+}
+
+// Squad is a full squad of starters and substitutes.
+type Squad struct {
+	Starting BestTeam
+	Bench    []StartingPlayer
+	Cost     float32
+	Bank     float32
+	Value    float32
+}
+
+// optimiseSquad picks the squad within budget with the best starting eleven value,
+// plus a little for the bench, trying every formation.
+func optimiseSquad(playerTypes []PlayerType, players []StartingPlayer, value func(StartingPlayer) float32, budget float32, constraints TeamConstraints) (Squad, error) {
+	if err := constraints.check(playerTypes, players, false); err != nil {
+		return Squad{}, err
+	}
+
+	var best selection
+	var bestFormation formation
+	found := false
+	for _, formation := range formations(playerTypes) {
+		if !constraints.allows(formation) {
+			continue
+		}
+		slots := make(map[PlayerTypeID][]float32, 0)
+		for _, playerType := range playerTypes {
+			weights := make([]float32, playerType.TeamPlayerCount)
+			for j := range weights {
+				if j < formation[playerType.ID] {
+					weights[j] = 1
+				} else {
+					weights[j] = squadBenchWeight
+				}
+			}
+			slots[playerType.ID] = weights
+		}
+
+		problem := selectionProblem{
+			candidates:  players,
+			value:       value,
+			slots:       slots,
+			budget:      budget,
+			constraints: constraints,
+		}
+		minValue := math.Inf(-1)
+		if found {
+			minValue = best.objective
+		}
+		if result, ok := problem.solve(minValue); ok {
+			best = result
+			bestFormation = formation
+			found = true
+		}
+	}
+
+	if !found {
+		return Squad{}, fmt.Errorf("no squad within £%.1fm meets the constraints", budget)
+	}
+
+	squad := Squad{
+		Cost:  best.cost,
+		Bank:  budget - best.cost,
+		Value: float32(best.value),
+	}
+	// players come back best first within each position
+	picked := make(map[PlayerTypeID]int, 0)
+	for _, player := range best.players {
+		picked[player.Player.Type.ID]++
+		if picked[player.Player.Type.ID] > bestFormation[player.Player.Type.ID] {
+			squad.Bench = append(squad.Bench, player)
+			continue
+		}
+		squad.Starting.Add(player)
+	}
+	squad.Bench = orderBench(squad.Bench)
+
+	return squad, nil
+}
+
+// bestLineup picks the highest scoring starting eleven, risk adjusted, from the
+// players in any legal formation that meets the constraints. When there aren't
+// enough players to fill a formation (e.g. blanks in a squad) it fills what it can.
+// Ties go to the earliest formation and then the lowest player ids, so the result
+// doesn't change between runs.
+func bestLineup(playerTypes []PlayerType, players []StartingPlayer, constraints TeamConstraints) (BestTeam, error) {
+	return bestLineupDifferentFrom(playerTypes, players, constraints, nil, 0)
+}
+
+// bestLineupDifferentFrom is bestLineup for a team with at least minDifference
+// players who aren't in each of the others.
+func bestLineupDifferentFrom(playerTypes []PlayerType, players []StartingPlayer, constraints TeamConstraints, others []BestTeam, minDifference int) (BestTeam, error) {
+	distinctFrom := make([]map[PlayerID]bool, 0, len(others))
+	for _, other := range others {
+		ids := make(map[PlayerID]bool, 0)
+		for _, player := range other.Players() {
+			ids[player.Player.ID] = true
+		}
+		distinctFrom = append(distinctFrom, ids)
+	}
+
+	if err := constraints.check(playerTypes, players, true); err != nil {
+		return BestTeam{}, err
+	}
+
+	var best selection
+	found := false
+	for _, formation := range formations(playerTypes) {
+		if !constraints.allows(formation) {
+			continue
+		}
+		slots := make(map[PlayerTypeID][]float32, 0)
+		for typeID, count := range formation {
+			weights := make([]float32, count)
+			for j := range weights {
+				weights[j] = 1
+			}
+			slots[typeID] = weights
+		}
+
+		problem := selectionProblem{
+			candidates:    players,
+			value:         StartingPlayer.RiskAdjustedScore,
+			slots:         slots,
+			constraints:   constraints,
+			partial:       true,
+			distinctFrom:  distinctFrom,
+			minDifference: minDifference,
+		}
+		minValue := math.Inf(-1)
+		if found {
+			minValue = best.objective
+		}
+		if result, ok := problem.solve(minValue); ok {
+			best = result
+			found = true
+		}
+	}
+
+	if !found {
+		return BestTeam{}, errors.New("no starting eleven meets the constraints")
+	}
+
+	var bestTeam BestTeam
+	for _, player := range best.players {
+		bestTeam.Add(player)
+	}
+	return bestTeam, nil
+}
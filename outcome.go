@@ -0,0 +1,67 @@
+package main
+
+import "math"
+
+const (
+	// how much each point of difference in FPL difficulty shifts the odds towards a win
+	resultDifficultyWeight = 0.6
+	resultHomeAdvantage    = 0.25
+	// draws are likeliest between evenly matched sides and get rarer as the gap grows
+	baseDrawChance    = 0.28
+	drawChanceFalloff = 0.04
+	minDrawChance     = 0.1
+)
+
+// ResultEstimate is the chance of each result for one side of a fixture.
+type ResultEstimate struct {
+	Win  float32
+	Draw float32
+	Loss float32
+}
+
+// LikelyWinner is the side with the easier fixture, or nil when it looks like a draw.
+func (f *Fixture) LikelyWinner() *Team {
+	if edge := f.DifficultyEdge(f.HomeTeam.ID); edge > 0 {
+		return f.HomeTeam
+	} else if edge < 0 {
+		return f.AwayTeam
+	}
+	return nil
+}
+
+// DifficultyEdge is how much harder the fixture is for the opposition than for the
+// given team, positive when the team has the easier game.
+func (f *Fixture) DifficultyEdge(teamID TeamID) float32 {
+	if teamID == f.AwayTeam.ID {
+		return f.Difficulty(f.HomeTeam.ID) - f.Difficulty(f.AwayTeam.ID)
+	}
+	return f.Difficulty(f.AwayTeam.ID) - f.Difficulty(f.HomeTeam.ID)
+}
+
+// Result estimates the outcome for the given team from the fixture difficulties.
+func (f *Fixture) Result(teamID TeamID) ResultEstimate {
+	edge := float64(f.DifficultyEdge(teamID)) * resultDifficultyWeight
+	if teamID == f.AwayTeam.ID {
+		edge -= resultHomeAdvantage
+	} else {
+		edge += resultHomeAdvantage
+	}
+
+	draw := math.Max(minDrawChance, baseDrawChance-drawChanceFalloff*math.Abs(edge))
+	win := (1 - draw) / (1 + math.Exp(-edge))
+
+	return ResultEstimate{
+		Win:  float32(win),
+		Draw: float32(draw),
+		Loss: float32(1 - draw - win),
+	}
+}
+
+// difficultyMultiplier rewards a likely win by the size of the difficulty gap and
+// penalises a likely loss by the same amount, so even fixtures have no effect.
+func (sp StartingPlayer) difficultyMultiplier() float32 {
+	// one more than the gap, so an even fixture multiplies by 1 and the loss term never divides by 0
+	difficultyMajority := float32(math.Abs(float64(sp.Fixture.DifficultyEdge(sp.Player.Team.ID))) + 1)
+	result := sp.Fixture.Result(sp.Player.Team.ID)
+	return result.Win*difficultyMajority + result.Draw + result.Loss/difficultyMajority
+}
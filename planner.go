@@ -0,0 +1,552 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rodaine/table"
+)
+
+const (
+	// FPL's limits on banking free transfers and the cost of each extra one
+	maxFreeTransfers = 5
+	transferHitCost  = 4
+	// how many plans the planner keeps after each gameweek
+	plannerBeamWidth = 30
+	// the most transfers the planner will make in one gameweek
+	plannerMaxTransfers = 3
+	// how many of the best options for each number of transfers the planner tries
+	plannerCombinations = 20
+	// a player is only worth buying if fewer than this many cheaper players are better
+	plannerShortlist = 3
+)
+
+// PlannedWeek is one gameweek of a transfer plan.
+type PlannedWeek struct {
+	Gameweek *Gameweek
+	// free transfers available before making any
+	FreeTransfers int
+	Out           []Player
+	In            []Player
+	// points deducted for transfers beyond the free ones
+	Hit  int
+	Bank float32
+	// the squad's expected points for the gameweek, before the hit
+	ExpectedPoints float32
+}
+
+// Rolled is whether the free transfer is saved for a later week.
+func (w PlannedWeek) Rolled() bool {
+	return len(w.In) == 0
+}
+
+type TransferPlan struct {
+	Weeks []PlannedWeek
+	// expected points over the horizon after hits
+	ExpectedPoints float32
+	// expected points over the horizon without making any transfers
+	Baseline float32
+}
+
+// transferPlanner searches for the best transfers over the coming gameweeks.
+type transferPlanner struct {
+	playerTypes []PlayerType
+	constraints TeamConstraints
+	gameweeks   []*Gameweek
+	// the formations the position caps allow
+	formations []formation
+	// each player's expected points in each gameweek, summed over double gameweeks
+	expected []map[PlayerID]float32
+	// the players worth buying in each position
+	shortlist map[PlayerTypeID][]Player
+}
+
+type plannerState struct {
+	squad         []Player
+	bank          float32
+	freeTransfers int
+	// net expected points so far
+	points float32
+	// points so far plus what the squad would score if it made no more transfers
+	estimate float32
+	weeks    []PlannedWeek
+}
+
+type plannedTransfer struct {
+	out   []Player
+	in    []Player
+	bank  float32
+	squad []Player
+}
+
+// newTransferPlanner works out every player's expected points over the horizon.
+func (d *Data) newTransferPlanner(from GameweekID, horizon int, constraints TeamConstraints) *transferPlanner {
+	planner := &transferPlanner{
+		playerTypes: d.PlayerTypes,
+		constraints: constraints,
+		shortlist:   make(map[PlayerTypeID][]Player, 0),
+	}
+	for _, f := range formations(d.PlayerTypes) {
+		if constraints.allows(f) {
+			planner.formations = append(planner.formations, f)
+		}
+	}
+	for gw := from; gw < from+GameweekID(horizon); gw++ {
+		gameweek := d.Gameweek(int(gw))
+		if gameweek == nil {
+			break
+		}
+		expected := make(map[PlayerID]float32, 0)
+		for _, player := range d.GameweekPlayers(int(gw)) {
+			expected[player.Player.ID] += player.ExpectedPoints()
+		}
+		planner.gameweeks = append(planner.gameweeks, gameweek)
+		planner.expected = append(planner.expected, expected)
+	}
+	return planner
+}
+
+// planTransfers looks for the sequence of transfers over the next horizon gameweeks
+// that earns the most expected points after hits. Each week it can roll the free
+// transfer, banking up to five, or make up to three transfers, paying four points
+// for each one beyond those free. The squad's prices should be what each player
+// would sell for. Plans are searched a gameweek at a time, keeping the most
+// promising ones, so the best plan isn't guaranteed but is usually found.
+func (d *Data) planTransfers(squad []Player, bank float32, freeTransfers int, from GameweekID, horizon int, constraints TeamConstraints) TransferPlan {
+	planner := d.newTransferPlanner(from, horizon, constraints)
+
+	plan := TransferPlan{}
+	if len(planner.gameweeks) == 0 {
+		return plan
+	}
+	for week := range planner.gameweeks {
+		plan.Baseline += planner.squadPoints(squad, week)
+	}
+	planner.shortlistPlayers(d.Players)
+
+	if freeTransfers > maxFreeTransfers {
+		freeTransfers = maxFreeTransfers
+	}
+	states := []plannerState{{
+		squad:         squad,
+		bank:          bank,
+		freeTransfers: freeTransfers,
+	}}
+	for week := range planner.gameweeks {
+		next := make([]plannerState, 0)
+		for _, state := range states {
+			for _, transfer := range planner.transfers(state, week) {
+				next = append(next, planner.apply(state, transfer, week))
+			}
+		}
+		if len(next) == 0 {
+			// the locked players can't all be bought in one gameweek
+			return TransferPlan{}
+		}
+		states = planner.prune(next)
+	}
+
+	best := states[0]
+	for _, state := range states {
+		if state.points > best.points {
+			best = state
+		}
+	}
+	plan.Weeks = best.weeks
+	plan.ExpectedPoints = best.points
+
+	return plan
+}
+
+// shortlistPlayers leaves out anyone who costs as much as several better players in
+// their position, as there's always a better use of the money, unless they're locked.
+func (p *transferPlanner) shortlistPlayers(players []Player) {
+	value := make(map[PlayerID]float32, 0)
+	byType := make(map[PlayerTypeID][]Player, 0)
+	for _, player := range players {
+		if _, ok := p.constraints.Excluded[player.ID]; ok {
+			continue
+		}
+		for _, expected := range p.expected {
+			value[player.ID] += expected[player.ID]
+		}
+		if _, locked := p.constraints.Locked[player.ID]; locked || value[player.ID] > 0 {
+			byType[player.Type.ID] = append(byType[player.Type.ID], player)
+		}
+	}
+
+	for typeID, candidates := range byType {
+		sort.Slice(candidates, func(i, j int) bool {
+			return value[candidates[i].ID] > value[candidates[j].ID]
+		})
+		for i, candidate := range candidates {
+			better := 0
+			for _, other := range candidates[:i] {
+				if other.RawCost <= candidate.RawCost {
+					better++
+				}
+			}
+			if _, locked := p.constraints.Locked[candidate.ID]; locked || better < plannerShortlist {
+				p.shortlist[typeID] = append(p.shortlist[typeID], candidate)
+			}
+		}
+	}
+}
+
+// transfers lists what the squad could do this week: nothing, or the best
+// combinations of single transfers. Locked players the squad doesn't have are
+// bought straight away, so until they are every transfer has to buy them.
+func (p *transferPlanner) transfers(state plannerState, week int) []plannedTransfer {
+	transfers := make([]plannedTransfer, 0)
+	mustBuy := p.constraints.lockedOutside(state.squad)
+	if len(mustBuy) == 0 {
+		transfers = append(transfers, plannedTransfer{squad: state.squad, bank: state.bank})
+	}
+
+	owned := make(map[PlayerID]bool, len(state.squad))
+	for _, player := range state.squad {
+		owned[player.ID] = true
+	}
+
+	// single transfers, only the best of which are kept along with any buying a
+	// locked player
+	singles := make([]plannedTransfer, 0)
+	buyingLocked := make([]plannedTransfer, 0)
+	for i, out := range state.squad {
+		if _, ok := p.constraints.Locked[out.ID]; ok {
+			continue
+		}
+		for _, in := range p.shortlist[out.Type.ID] {
+			bank := roundMoney(state.bank + out.RawCost - in.RawCost)
+			if owned[in.ID] || bank < 0 {
+				continue
+			}
+			squad := make([]Player, len(state.squad))
+			copy(squad, state.squad)
+			squad[i] = in
+			if !p.withinClubCaps(squad) {
+				continue
+			}
+			single := plannedTransfer{
+				out:   []Player{out},
+				in:    []Player{in},
+				bank:  bank,
+				squad: squad,
+			}
+			if _, ok := p.constraints.Locked[in.ID]; ok {
+				buyingLocked = append(buyingLocked, single)
+			} else {
+				singles = append(singles, single)
+			}
+		}
+	}
+	singles = append(buyingLocked, p.best(singles, state.squad, week)...)
+	transfers = append(transfers, p.best(buyingAll(singles, mustBuy, 0), state.squad, week)...)
+
+	// build multiple transfers out of the best singles, each combination once
+	previous := buyingAll(singles, mustBuy, plannerMaxTransfers-1)
+	for k := 2; k <= plannerMaxTransfers; k++ {
+		combined := make([]plannedTransfer, 0)
+		seen := make(map[string]bool, 0)
+		for _, transfer := range previous {
+			for _, single := range singles {
+				next, ok := p.combine(transfer, single)
+				if !ok {
+					continue
+				}
+				key := transferKey(next)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				combined = append(combined, next)
+			}
+		}
+		transfers = append(transfers, p.best(buyingAll(combined, mustBuy, 0), state.squad, week)...)
+		// only combinations that can still buy the rest of the locked players are built on
+		previous = p.best(buyingAll(combined, mustBuy, plannerMaxTransfers-k), state.squad, week)
+	}
+
+	return transfers
+}
+
+// buyingAll keeps the transfers that leave at most the given number of the players
+// still to buy.
+func buyingAll(transfers []plannedTransfer, players []Player, left int) []plannedTransfer {
+	if len(players) <= left {
+		return transfers
+	}
+	kept := make([]plannedTransfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		bought := make(map[PlayerID]bool, len(transfer.in))
+		for _, player := range transfer.in {
+			bought[player.ID] = true
+		}
+		missing := 0
+		for _, player := range players {
+			if !bought[player.ID] {
+				missing++
+			}
+		}
+		if missing <= left {
+			kept = append(kept, transfer)
+		}
+	}
+	return kept
+}
+
+// best keeps the transfers that add the most over the rest of the horizon.
+func (p *transferPlanner) best(transfers []plannedTransfer, squad []Player, week int) []plannedTransfer {
+	current := p.remainingPoints(squad, week)
+	gains := make([]float32, len(transfers))
+	for i, transfer := range transfers {
+		gains[i] = p.remainingPoints(transfer.squad, week) - current
+	}
+	sort.Stable(byGain{transfers, gains})
+	if len(transfers) > plannerCombinations {
+		transfers = transfers[:plannerCombinations]
+	}
+	return transfers
+}
+
+// combine adds a single transfer to the others, if it sells and buys different
+// players and the squad can still afford it.
+func (p *transferPlanner) combine(transfer plannedTransfer, single plannedTransfer) (plannedTransfer, bool) {
+	out, in := single.out[0], single.in[0]
+	for i := range transfer.out {
+		if transfer.out[i].ID == out.ID || transfer.in[i].ID == in.ID {
+			return plannedTransfer{}, false
+		}
+	}
+	// keep each combination in one order so it's only built once
+	if transfer.out[len(transfer.out)-1].ID > out.ID {
+		return plannedTransfer{}, false
+	}
+
+	combined := plannedTransfer{
+		out:   append(append([]Player{}, transfer.out...), out),
+		in:    append(append([]Player{}, transfer.in...), in),
+		bank:  roundMoney(transfer.bank + out.RawCost - in.RawCost),
+		squad: make([]Player, len(transfer.squad)),
+	}
+	if combined.bank < 0 {
+		return plannedTransfer{}, false
+	}
+	copy(combined.squad, transfer.squad)
+	for i := range combined.squad {
+		if combined.squad[i].ID == out.ID {
+			combined.squad[i] = in
+		}
+	}
+	if !p.withinClubCaps(combined.squad) {
+		return plannedTransfer{}, false
+	}
+	return combined, true
+}
+
+// apply makes the transfers and plays the gameweek.
+func (p *transferPlanner) apply(state plannerState, transfer plannedTransfer, week int) plannerState {
+	made := len(transfer.in)
+	hit := 0
+	if made > state.freeTransfers {
+		hit = (made - state.freeTransfers) * transferHitCost
+	}
+	// an unused free transfer carries over, up to the limit
+	freeTransfers := state.freeTransfers - made
+	if freeTransfers < 0 {
+		freeTransfers = 0
+	}
+	freeTransfers++
+	if freeTransfers > maxFreeTransfers {
+		freeTransfers = maxFreeTransfers
+	}
+
+	expected := p.squadPoints(transfer.squad, week)
+	next := plannerState{
+		squad:         transfer.squad,
+		bank:          transfer.bank,
+		freeTransfers: freeTransfers,
+		points:        state.points + expected - float32(hit),
+		weeks: append(append([]PlannedWeek{}, state.weeks...), PlannedWeek{
+			Gameweek:       p.gameweeks[week],
+			FreeTransfers:  state.freeTransfers,
+			Out:            transfer.out,
+			In:             transfer.in,
+			Hit:            hit,
+			Bank:           transfer.bank,
+			ExpectedPoints: expected,
+		}),
+	}
+	next.estimate = next.points + p.remainingPoints(transfer.squad, week+1)
+	return next
+}
+
+// prune keeps the most promising plans, dropping any that reach the same squad
+// with fewer points.
+func (p *transferPlanner) prune(states []plannerState) []plannerState {
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].estimate > states[j].estimate
+	})
+	kept := make([]plannerState, 0, plannerBeamWidth)
+	seen := make(map[string]bool, 0)
+	for _, state := range states {
+		key := fmt.Sprintf("%s-%d-%.1f", squadKey(state.squad), state.freeTransfers, state.bank)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, state)
+		if len(kept) == plannerBeamWidth {
+			break
+		}
+	}
+	return kept
+}
+
+// squadPoints is the expected points of the best eleven the squad can field in the
+// gameweek, with a little credit for the bench.
+func (p *transferPlanner) squadPoints(squad []Player, week int) float32 {
+	return p.lineupPoints(p.pointsByType(squad, week))
+}
+
+// pointsByType is the squad's expected points in the week for each position, best first.
+func (p *transferPlanner) pointsByType(squad []Player, week int) map[PlayerTypeID][]float32 {
+	byType := make(map[PlayerTypeID][]float32, 0)
+	for _, player := range squad {
+		byType[player.Type.ID] = append(byType[player.Type.ID], p.expected[week][player.ID])
+	}
+	for _, points := range byType {
+		sort.Slice(points, func(i, j int) bool {
+			return points[i] > points[j]
+		})
+	}
+	return byType
+}
+
+// lineupPoints is squadPoints for each position's expected points, best first.
+func (p *transferPlanner) lineupPoints(byType map[PlayerTypeID][]float32) float32 {
+	starting, bench := p.lineup(byType)
+	return starting + bench*squadBenchWeight
+}
+
+// lineup splits the expected points between the best eleven and the bench.
+func (p *transferPlanner) lineup(byType map[PlayerTypeID][]float32) (float32, float32) {
+	var total float32
+	for _, points := range byType {
+		for _, point := range points {
+			total += point
+		}
+	}
+
+	var best float32
+	for i, f := range p.formations {
+		var starting float32
+		for typeID, count := range f {
+			for j := 0; j < count && j < len(byType[typeID]); j++ {
+				starting += byType[typeID][j]
+			}
+		}
+		if i == 0 || starting > best {
+			best = starting
+		}
+	}
+	return best, total - best
+}
+
+// remainingPoints is what the squad would score from the week to the end of the
+// horizon without any more transfers.
+func (p *transferPlanner) remainingPoints(squad []Player, week int) float32 {
+	var total float32
+	for ; week < len(p.gameweeks); week++ {
+		total += p.squadPoints(squad, week)
+	}
+	return total
+}
+
+func (p *transferPlanner) withinClubCaps(squad []Player) bool {
+	counts := make(map[TeamID]int, 0)
+	for _, player := range squad {
+		counts[player.Team.ID]++
+		if counts[player.Team.ID] > p.constraints.clubCap(player.Team.ID) {
+			return false
+		}
+	}
+	return true
+}
+
+type byGain struct {
+	transfers []plannedTransfer
+	gains     []float32
+}
+
+func (b byGain) Len() int           { return len(b.transfers) }
+func (b byGain) Less(i, j int) bool { return b.gains[i] > b.gains[j] }
+func (b byGain) Swap(i, j int) {
+	b.transfers[i], b.transfers[j] = b.transfers[j], b.transfers[i]
+	b.gains[i], b.gains[j] = b.gains[j], b.gains[i]
+}
+
+// roundMoney rounds to the nearest £0.1m, so sums of prices compare exactly.
+func roundMoney(m float32) float32 {
+	return float32(math.Round(float64(m)*10) / 10)
+}
+
+func squadKey(squad []Player) string {
+	ids := make([]int, 0, len(squad))
+	for _, player := range squad {
+		ids = append(ids, int(player.ID))
+	}
+	sort.Ints(ids)
+	return fmt.Sprint(ids)
+}
+
+func transferKey(transfer plannedTransfer) string {
+	return fmt.Sprintf("%s>%s", squadKey(transfer.out), squadKey(transfer.in))
+}
+
+func playerNames(players []Player) string {
+	names := make([]string, 0, len(players))
+	for _, player := range players {
+		names = append(names, player.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func printTransferPlan(plan TransferPlan) {
+	if len(plan.Weeks) == 0 {
+		return
+	}
+	headerFmt, columnFmt := tableFormat()
+
+	first, last := plan.Weeks[0].Gameweek, plan.Weeks[len(plan.Weeks)-1].Gameweek
+	fmt.Printf("\nTransfer plan for %s to %s:\n", first.Name, last.Name)
+	tbl := table.New("Gameweek", "Free", "Out", "In", "Hit", "Bank", "xP")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, week := range plan.Weeks {
+		out, in := playerNames(week.Out), playerNames(week.In)
+		if week.Rolled() {
+			out, in = "Roll", "-"
+		}
+		hit := "-"
+		if week.Hit > 0 {
+			hit = fmt.Sprintf("-%d", week.Hit)
+		}
+		tbl.AddRow(
+			week.Gameweek.Name,
+			week.FreeTransfers,
+			out,
+			in,
+			hit,
+			fmt.Sprintf("£%.1fm", week.Bank),
+			fmt.Sprintf("%.1f", week.ExpectedPoints),
+		)
+	}
+	tbl.Print()
+	fmt.Printf(
+		"\nThe plan should score %.1f points after hits, %.1f more than making no transfers.\n",
+		plan.ExpectedPoints,
+		plan.ExpectedPoints-plan.Baseline,
+	)
+}
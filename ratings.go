@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	fplRatings    = "fpl"
+	customRatings = "custom"
+	// how many gameweeks old a result is when it counts half as much as the latest
+	ratingHalfLife = 6.0
+	// matches at the league average every team starts with, so a few results can't
+	// make a rating extreme
+	ratingPriorMatches = 4.0
+	// how far a rating moves each time the expected goals double or halve
+	ratingSpread  = 2.0
+	minDifficulty = 1
+	maxDifficulty = 5
+)
+
+// TeamStrength is how many goals a team scores and concedes at home and away, as a
+// multiple of the league average.
+type TeamStrength struct {
+	HomeAttack  float64
+	HomeDefence float64
+	AwayAttack  float64
+	AwayDefence float64
+}
+
+// FixtureRatings rates fixtures from the results so far, separately for attack, how
+// hard it should be to score, and defence, how hard to keep the opponent out.
+type FixtureRatings struct {
+	Strengths map[TeamID]TeamStrength
+	// the league's average goals in a match for the home and away sides
+	HomeGoals float64
+	AwayGoals float64
+}
+
+// RateFixtures works out every team's strengths from the finished fixtures, counting
+// the more recent ones for more.
+func (d *Data) RateFixtures() (FixtureRatings, error) {
+	var latest GameweekID
+	for _, fixture := range d.Fixtures {
+		if fixture.Finished && fixture.Gameweek.ID > latest {
+			latest = fixture.Gameweek.ID
+		}
+	}
+
+	type goals struct {
+		homeScored, homeConceded, homeWeight float64
+		awayScored, awayConceded, awayWeight float64
+	}
+	teamGoals := make(map[TeamID]*goals, len(d.Teams))
+	for _, team := range d.Teams {
+		teamGoals[team.ID] = &goals{}
+	}
+
+	var homeGoals, awayGoals, weight float64
+	for _, fixture := range d.Fixtures {
+		if !fixture.Finished {
+			continue
+		}
+		w := math.Pow(0.5, float64(latest-fixture.Gameweek.ID)/ratingHalfLife)
+		home, away := float64(fixture.HomeTeamScore), float64(fixture.AwayTeamScore)
+		homeGoals += w * home
+		awayGoals += w * away
+		weight += w
+
+		if g, ok := teamGoals[fixture.HomeTeam.ID]; ok {
+			g.homeScored += w * home
+			g.homeConceded += w * away
+			g.homeWeight += w
+		}
+		if g, ok := teamGoals[fixture.AwayTeam.ID]; ok {
+			g.awayScored += w * away
+			g.awayConceded += w * home
+			g.awayWeight += w
+		}
+	}
+	if weight == 0 || homeGoals == 0 || awayGoals == 0 {
+		return FixtureRatings{}, fmt.Errorf("there aren't enough results yet to rate fixtures from")
+	}
+
+	ratings := FixtureRatings{
+		Strengths: make(map[TeamID]TeamStrength, len(d.Teams)),
+		HomeGoals: homeGoals / weight,
+		AwayGoals: awayGoals / weight,
+	}
+	// relative is the team's goals per match as a share of the average, after the prior
+	relative := func(scored float64, matches float64, average float64) float64 {
+		return (scored + ratingPriorMatches*average) / ((matches + ratingPriorMatches) * average)
+	}
+	for teamID, g := range teamGoals {
+		ratings.Strengths[teamID] = TeamStrength{
+			HomeAttack:  relative(g.homeScored, g.homeWeight, ratings.HomeGoals),
+			HomeDefence: relative(g.homeConceded, g.homeWeight, ratings.AwayGoals),
+			AwayAttack:  relative(g.awayScored, g.awayWeight, ratings.AwayGoals),
+			AwayDefence: relative(g.awayConceded, g.awayWeight, ratings.HomeGoals),
+		}
+	}
+
+	return ratings, nil
+}
+
+// ExpectedGoals is how many goals the team should score and concede in the fixture.
+func (r *FixtureRatings) ExpectedGoals(fixture *Fixture, teamID TeamID) (float64, float64) {
+	home, ok := r.Strengths[fixture.HomeTeam.ID]
+	if !ok {
+		home = TeamStrength{1, 1, 1, 1}
+	}
+	away, ok := r.Strengths[fixture.AwayTeam.ID]
+	if !ok {
+		away = TeamStrength{1, 1, 1, 1}
+	}
+	homeExpected := r.HomeGoals * home.HomeAttack * away.AwayDefence
+	awayExpected := r.AwayGoals * away.AwayAttack * home.HomeDefence
+	if teamID == fixture.AwayTeam.ID {
+		return awayExpected, homeExpected
+	}
+	return homeExpected, awayExpected
+}
+
+// rating puts expected goals on FPL's scale, 3 for the league average and easier
+// for attack the more goals there should be, harder for defence.
+func (r *FixtureRatings) rating(expectedGoals float64, attack bool) float32 {
+	change := ratingSpread * math.Log2(expectedGoals/((r.HomeGoals+r.AwayGoals)/2))
+	if attack {
+		change = -change
+	}
+	return float32(math.Max(minDifficulty, math.Min(maxDifficulty, 3+change)))
+}
+
+// AttackDifficulty is how hard it should be for the team to score in the fixture, 1
+// to 5, from FPL's difficulty or the custom ratings.
+func (f *Fixture) AttackDifficulty(teamID TeamID) float32 {
+	if scoringConfig.Ratings == nil {
+		return float32(f.TeamDifficulty(teamID))
+	}
+	scored, _ := scoringConfig.Ratings.ExpectedGoals(f, teamID)
+	return scoringConfig.Ratings.rating(scored, true)
+}
+
+// DefenceDifficulty is how hard it should be for the team to keep the opponent out.
+func (f *Fixture) DefenceDifficulty(teamID TeamID) float32 {
+	if scoringConfig.Ratings == nil {
+		return float32(f.TeamDifficulty(teamID))
+	}
+	_, conceded := scoringConfig.Ratings.ExpectedGoals(f, teamID)
+	return scoringConfig.Ratings.rating(conceded, false)
+}
+
+// Difficulty is the fixture's overall difficulty for the team, FPL's or the average
+// of the custom attack and defence ratings.
+func (f *Fixture) Difficulty(teamID TeamID) float32 {
+	if scoringConfig.Ratings == nil {
+		return float32(f.TeamDifficulty(teamID))
+	}
+	return (f.AttackDifficulty(teamID) + f.DefenceDifficulty(teamID)) / 2
+}
+
+// Majority is the gap between the two sides' difficulties to the nearest whole
+// number, which is FPL's DifficultyMajority unless the ratings are custom.
+func (f *Fixture) Majority() int {
+	if scoringConfig.Ratings == nil {
+		return f.DifficultyMajority
+	}
+	return int(math.Round(math.Abs(float64(f.DifficultyEdge(f.HomeTeam.ID)))))
+}
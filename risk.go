@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// fewest appearances before a player's own spread of points is trusted
+	minVarianceMatches = 3
+	// standard deviation as a share of mean points, for players without enough matches
+	defaultPointsVariation = 1.0
+)
+
+// PointsVariance is how much a player's points in a fixture could swing, from how
+// volatile their points have been and the chance they don't play at all.
+type PointsVariance struct {
+	// points in the matches they played
+	PlayingMean   float32
+	PlayingStdDev float32
+	// chance they get on the pitch
+	PlayChance float32
+	Mean       float32
+	StdDev     float32
+}
+
+// CoefficientOfVariation is the standard deviation as a share of the mean.
+func (v PointsVariance) CoefficientOfVariation() float32 {
+	if v.Mean <= 0 {
+		return 0
+	}
+	return v.StdDev / v.Mean
+}
+
+func (sp StartingPlayer) Variance() PointsVariance {
+	cacheKey := fmt.Sprintf("variance_player_%d_%d", sp.Player.ID, sp.Fixture.ID)
+	if val, exists := cache[cacheKey]; exists {
+		return val.(PointsVariance)
+	}
+
+	minutes := sp.Minutes()
+	variance := PointsVariance{
+		PlayChance: minutes.ChanceOfPlaying * (minutes.StartRate + (1-minutes.StartRate)*minutes.SubAppearanceRate),
+	}
+
+	points := make([]float64, 0)
+	for _, match := range sp.Player.History {
+		if match.Played {
+			points = append(points, float64(match.Points))
+		}
+	}
+	if len(points) >= minVarianceMatches {
+		var sum, sumSquares float64
+		for _, p := range points {
+			sum += p
+			sumSquares += p * p
+		}
+		mean := sum / float64(len(points))
+		// sample variance, as a handful of matches understates the spread
+		spread := (sumSquares - float64(len(points))*mean*mean) / float64(len(points)-1)
+		variance.PlayingMean = float32(mean)
+		variance.PlayingStdDev = float32(math.Sqrt(math.Max(spread, 0)))
+	} else {
+		variance.PlayingMean = sp.Player.PointsPerGame
+		variance.PlayingStdDev = sp.Player.PointsPerGame * defaultPointsVariation
+	}
+
+	// points are a mix of nothing when they don't play and their usual spread when they do
+	q := float64(variance.PlayChance)
+	mean := float64(variance.PlayingMean)
+	stdDev := float64(variance.PlayingStdDev)
+	variance.Mean = float32(q * mean)
+	variance.StdDev = float32(math.Sqrt(q*stdDev*stdDev + q*(1-q)*mean*mean))
+
+	cache[cacheKey] = variance
+
+	return variance
+}
+
+// RiskAdjustedScore is the score less the risk aversion times its standard deviation,
+// taking the score's spread to be in proportion to the points'. A negative risk
+// aversion favours volatile players instead.
+func (sp StartingPlayer) RiskAdjustedScore() float32 {
+	score := sp.Score()
+	if scoringConfig.RiskAversion == 0 {
+		return score
+	}
+	multiplier := 1 - scoringConfig.RiskAversion*sp.Variance().CoefficientOfVariation()
+	if multiplier < 0 {
+		multiplier = 0
+	}
+	return score * multiplier
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rodaine/table"
+)
+
+// SetPieceOrders is where a player sits in their team's order for each set piece,
+// 1 being first choice and 0 meaning they aren't on the list.
+type SetPieceOrders struct {
+	Penalties                   int
+	CornersAndIndirectFreekicks int
+	DirectFreekicks             int
+}
+
+// how much being first or second choice for each set piece is worth, relative to
+// being the first choice penalty taker
+var setPieceWeights = []struct {
+	name   string
+	order  func(SetPieceOrders) int
+	first  float32
+	second float32
+}{
+	{"Penalties", func(o SetPieceOrders) int { return o.Penalties }, 1, 0.25},
+	{"Direct free kicks", func(o SetPieceOrders) int { return o.DirectFreekicks }, 0.4, 0.1},
+	{"Corners & indirect", func(o SetPieceOrders) int { return o.CornersAndIndirectFreekicks }, 0.3, 0.1},
+}
+
+// Weight is how valuable the player's set piece duties are, 0 for none.
+func (o SetPieceOrders) Weight() float32 {
+	var weight float32
+	for _, setPiece := range setPieceWeights {
+		switch setPiece.order(o) {
+		case 1:
+			weight += setPiece.first
+		case 2:
+			weight += setPiece.second
+		}
+	}
+	return weight
+}
+
+// setPieceOverrides maps a team's name or short name to its takers, in order, for
+// any set piece where the API is behind the team news. A listed set piece replaces
+// the team's whole order for it.
+type setPieceOverrides map[string]struct {
+	Penalties                   []string `json:"penalties"`
+	CornersAndIndirectFreekicks []string `json:"corners_and_indirect_freekicks"`
+	DirectFreekicks             []string `json:"direct_freekicks"`
+}
+
+// LoadSetPieceOverrides applies the overrides in the given file, if it exists.
+func (d *Data) LoadSetPieceOverrides(path string) error {
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var overrides setPieceOverrides
+	if err := json.Unmarshal(body, &overrides); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	for teamName, takers := range overrides {
+		var team *Team
+		for _, t := range d.Teams {
+			if strings.EqualFold(t.Name, teamName) || strings.EqualFold(t.ShortName, teamName) {
+				team = t
+			}
+		}
+		if team == nil {
+			return fmt.Errorf("set piece overrides: team '%s' not found", teamName)
+		}
+
+		orders := make(map[PlayerID]SetPieceOrders, 0)
+		for _, player := range team.Players {
+			orders[player.ID] = player.SetPieces
+		}
+
+		overrideOrder := func(names []string, set func(*SetPieceOrders, int)) error {
+			if names == nil {
+				return nil
+			}
+			for playerID, order := range orders {
+				set(&order, 0)
+				orders[playerID] = order
+			}
+			for i, name := range names {
+				player, ok := findTeamPlayer(team, name)
+				if !ok {
+					return fmt.Errorf("set piece overrides: player '%s' not found in %s", name, team.Name)
+				}
+				order := orders[player.ID]
+				set(&order, i+1)
+				orders[player.ID] = order
+			}
+			return nil
+		}
+
+		if err := overrideOrder(takers.Penalties, func(o *SetPieceOrders, n int) { o.Penalties = n }); err != nil {
+			return err
+		}
+		if err := overrideOrder(takers.CornersAndIndirectFreekicks, func(o *SetPieceOrders, n int) { o.CornersAndIndirectFreekicks = n }); err != nil {
+			return err
+		}
+		if err := overrideOrder(takers.DirectFreekicks, func(o *SetPieceOrders, n int) { o.DirectFreekicks = n }); err != nil {
+			return err
+		}
+
+		for i := range team.Players {
+			team.Players[i].SetPieces = orders[team.Players[i].ID]
+		}
+		for i := range d.Players {
+			if order, ok := orders[d.Players[i].ID]; ok {
+				d.Players[i].SetPieces = order
+			}
+		}
+	}
+
+	return nil
+}
+
+func findTeamPlayer(team *Team, name string) (Player, bool) {
+	for _, player := range team.Players {
+		if matchesName(player.Name, name) {
+			return player, true
+		}
+	}
+	return Player{}, false
+}
+
+// setPieceTakers lists a team's takers for one set piece in order, e.g. "1. Saka, 2. Havertz".
+func setPieceTakers(team *Team, order func(SetPieceOrders) int) string {
+	takers := make(map[int]string, 0)
+	last := 0
+	for _, player := range team.Players {
+		if n := order(player.SetPieces); n > 0 {
+			takers[n] = player.Name
+			if n > last {
+				last = n
+			}
+		}
+	}
+
+	names := make([]string, 0)
+	for n := 1; n <= last; n++ {
+		if name, ok := takers[n]; ok {
+			names = append(names, fmt.Sprintf("%d. %s", n, name))
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ", ")
+}
+
+func printSetPieces(teams []*Team) {
+	headerFmt, columnFmt := tableFormat()
+	headers := []interface{}{"Team"}
+	for _, setPiece := range setPieceWeights {
+		headers = append(headers, setPiece.name)
+	}
+	tbl := table.New(headers...)
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, team := range teams {
+		row := []interface{}{team.Name}
+		for _, setPiece := range setPieceWeights {
+			row = append(row, setPieceTakers(team, setPiece.order))
+		}
+		tbl.AddRow(row...)
+	}
+	fmt.Println()
+	tbl.Print()
+	fmt.Println()
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/rodaine/table"
+)
+
+const (
+	leagueAverageGoals = 1.4
+	// matches' worth of league-average results mixed into each team's record
+	teamGoalsPriorMatches = 4
+	homeGoalsFactor       = 1.1
+	awayGoalsFactor       = 0.9
+	// how much a point of FPL difficulty edge changes a team's expected goals, applied
+	// in proportion to how little real data there is about the two teams
+	difficultyGoalsEffect = 0.15
+	assistedGoalChance    = 0.75
+	// minutes' worth of positional averages mixed into each player's per 90 rates
+	playerRatePriorMinutes = 270
+	defaultSubMinutes      = 20
+	earlySubMinutes        = 45
+	// each side starts one goalkeeper and ten outfield players and can bring on five
+	startingOutfield = 10
+	maxSubstitutes   = 5
+	// iterations used when all that's needed is each player's mean
+	expectedPointsIterations = 2000
+)
+
+type positionRules struct {
+	goalPoints        int
+	cleanSheetPoints  int
+	concededPenalty   bool
+	savePoints        bool
+	goalsPer90Prior   float64
+	assistsPer90Prior float64
+}
+
+// FPL's points for each position, along with a typical player's output
+var scoringRules = map[string]positionRules{
+	"Goalkeeper": {goalPoints: 10, cleanSheetPoints: 4, concededPenalty: true, savePoints: true, goalsPer90Prior: 0, assistsPer90Prior: 0.01},
+	"Defender":   {goalPoints: 6, cleanSheetPoints: 4, concededPenalty: true, goalsPer90Prior: 0.04, assistsPer90Prior: 0.06},
+	"Midfielder": {goalPoints: 5, cleanSheetPoints: 1, goalsPer90Prior: 0.15, assistsPer90Prior: 0.15},
+	"Forward":    {goalPoints: 4, goalsPer90Prior: 0.35, assistsPer90Prior: 0.12},
+}
+
+type teamRecord struct {
+	scored   float64
+	conceded float64
+	matches  int
+}
+
+func recordForTeam(team *Team) teamRecord {
+	cacheKey := fmt.Sprintf("team_record_%d", team.ID)
+	if val, exists := cache[cacheKey]; exists {
+		return val.(teamRecord)
+	}
+
+	var record teamRecord
+	for _, fixture := range team.Fixtures {
+		if !fixture.Finished {
+			continue
+		}
+		if fixture.HomeTeam.ID == team.ID {
+			record.scored += float64(fixture.HomeTeamScore)
+			record.conceded += float64(fixture.AwayTeamScore)
+		} else {
+			record.scored += float64(fixture.AwayTeamScore)
+			record.conceded += float64(fixture.HomeTeamScore)
+		}
+		record.matches++
+	}
+
+	cache[cacheKey] = record
+
+	return record
+}
+
+// expectedGoals is how many goals the given side should score in the fixture, from
+// its attack and the opposition's defence so far this season.
+func expectedGoals(fixture Fixture, teamID TeamID) float64 {
+	team, opposition := fixture.HomeTeam, fixture.AwayTeam
+	venueFactor := homeGoalsFactor
+	if teamID == fixture.AwayTeam.ID {
+		team, opposition = fixture.AwayTeam, fixture.HomeTeam
+		venueFactor = awayGoalsFactor
+	}
+
+	teamRecord := recordForTeam(team)
+	oppositionRecord := recordForTeam(opposition)
+
+	attack := (teamRecord.scored + leagueAverageGoals*teamGoalsPriorMatches) /
+		float64(teamRecord.matches+teamGoalsPriorMatches)
+	defence := (oppositionRecord.conceded + leagueAverageGoals*teamGoalsPriorMatches) /
+		float64(oppositionRecord.matches+teamGoalsPriorMatches)
+
+	fewestMatches := teamRecord.matches
+	if oppositionRecord.matches < fewestMatches {
+		fewestMatches = oppositionRecord.matches
+	}
+	priorShare := float64(teamGoalsPriorMatches) / float64(teamGoalsPriorMatches+fewestMatches)
+	difficulty := math.Exp(difficultyGoalsEffect * float64(fixture.DifficultyEdge(teamID)) * priorShare)
+
+	return attack * defence / leagueAverageGoals * venueFactor * difficulty
+}
+
+type playerSimulationModel struct {
+	player          Player
+	rules           positionRules
+	startChance     float64
+	fullMatchChance float64
+	subChance       float64
+	startMinutes    float64
+	subMinutes      float64
+	goalsPer90      float64
+	assistsPer90    float64
+	savesPer90      float64
+	yellowsPer90    float64
+	bps             bpsModel
+}
+
+func newPlayerSimulationModel(sp StartingPlayer) playerSimulationModel {
+	minutes := sp.Minutes()
+	rules := scoringRules[sp.Player.Type.Name]
+	stats := sp.Player.Stats
+
+	model := playerSimulationModel{
+		player:          sp.Player,
+		rules:           rules,
+		startChance:     float64(minutes.ChanceOfPlaying * minutes.StartRate),
+		fullMatchChance: float64(1 - minutes.EarlySubRate),
+		subChance:       float64(minutes.ChanceOfPlaying * (1 - minutes.StartRate) * minutes.SubAppearanceRate),
+		startMinutes:    math.Max(60, float64(minutes.AverageStartingMinutes)),
+		subMinutes:      float64(minutes.AverageSubMinutes),
+		bps:             newBPSModel(sp.Player),
+	}
+	if model.subMinutes == 0 {
+		model.subMinutes = defaultSubMinutes
+	}
+
+	nineties := float64(stats.Minutes+playerRatePriorMinutes) / 90
+	priorNineties := float64(playerRatePriorMinutes) / 90
+	model.goalsPer90 = (float64(stats.Goals) + rules.goalsPer90Prior*priorNineties) / nineties
+	model.assistsPer90 = (float64(stats.Assists) + rules.assistsPer90Prior*priorNineties) / nineties
+	if stats.Minutes > 0 {
+		model.savesPer90 = float64(stats.Saves) / float64(stats.Minutes) * 90
+		model.yellowsPer90 = float64(stats.YellowCards) / float64(stats.Minutes) * 90
+	}
+
+	return model
+}
+
+// sampleLineup picks the side's eleven, one goalkeeper and ten outfield players each
+// chosen in proportion to how likely they are to start, and brings outfield players
+// on from the bench, returning everyone's minutes.
+func sampleLineup(rng *rand.Rand, models []playerSimulationModel) []float64 {
+	minutes := make([]float64, len(models))
+	keeperWeights := make([]float64, len(models))
+	outfieldWeights := make([]float64, len(models))
+	for i, model := range models {
+		if model.player.Type.Name == "Goalkeeper" {
+			keeperWeights[i] = model.startChance
+		} else {
+			outfieldWeights[i] = model.startChance
+		}
+	}
+
+	started := make([]bool, len(models))
+	start := func(i int) {
+		started[i] = true
+		outfieldWeights[i] = 0
+		minutes[i] = earlySubMinutes
+		if rng.Float64() < models[i].fullMatchChance {
+			minutes[i] = models[i].startMinutes
+		}
+	}
+	if keeper := weightedChoice(rng, keeperWeights, -1); keeper >= 0 {
+		start(keeper)
+	}
+	for n := 0; n < startingOutfield; n++ {
+		starter := weightedChoice(rng, outfieldWeights, -1)
+		if starter < 0 {
+			break
+		}
+		start(starter)
+	}
+
+	// a substitute's chance of coming on is given they didn't start
+	substitutes := 0
+	for _, i := range rng.Perm(len(models)) {
+		model := models[i]
+		if substitutes == maxSubstitutes {
+			break
+		}
+		if started[i] || model.player.Type.Name == "Goalkeeper" || model.startChance >= 1 {
+			continue
+		}
+		if rng.Float64() < model.subChance/(1-model.startChance) {
+			minutes[i] = model.subMinutes
+			substitutes++
+		}
+	}
+
+	return minutes
+}
+
+// fixtureSimulation holds every player's points in each simulated playing of a
+// fixture. Each side fields one eleven in each iteration and its players share the
+// same team goals, so their points rise and fall together, and bonus goes to the
+// top BPS across both teams.
+type fixtureSimulation struct {
+	points        map[PlayerID][]float32
+	played        map[PlayerID][]bool
+	expectedBonus map[PlayerID]float32
+}
+
+func simulateFixture(fixture Fixture, iterations int) fixtureSimulation {
+	cacheKey := fmt.Sprintf("simulation_fixture_%d_%d", fixture.ID, iterations)
+	if val, exists := cache[cacheKey]; exists {
+		return val.(fixtureSimulation)
+	}
+
+	// seeded by fixture so that the same data always gives the same numbers
+	rng := rand.New(rand.NewSource(int64(fixture.ID)))
+
+	type side struct {
+		models        []playerSimulationModel
+		expectedGoals float64
+	}
+	sides := []side{
+		{expectedGoals: expectedGoals(fixture, fixture.HomeTeam.ID)},
+		{expectedGoals: expectedGoals(fixture, fixture.AwayTeam.ID)},
+	}
+	for i, team := range []*Team{fixture.HomeTeam, fixture.AwayTeam} {
+		opposition := fixture.AwayTeam
+		if i == 1 {
+			opposition = fixture.HomeTeam
+		}
+		for _, player := range team.Players {
+			sides[i].models = append(sides[i].models, newPlayerSimulationModel(StartingPlayer{
+				Player:       player,
+				Fixture:      fixture,
+				OpposingTeam: *opposition,
+			}))
+		}
+	}
+
+	simulation := fixtureSimulation{
+		points:        make(map[PlayerID][]float32, 0),
+		played:        make(map[PlayerID][]bool, 0),
+		expectedBonus: make(map[PlayerID]float32, 0),
+	}
+	for _, side := range sides {
+		for _, model := range side.models {
+			simulation.points[model.player.ID] = make([]float32, iterations)
+			simulation.played[model.player.ID] = make([]bool, iterations)
+		}
+	}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		goals := []int{
+			poisson(rng, sides[0].expectedGoals),
+			poisson(rng, sides[1].expectedGoals),
+		}
+
+		minutes := make([][]float64, len(sides))
+		playerGoals := make([][]int, len(sides))
+		playerAssists := make([][]int, len(sides))
+		playerSaves := make([][]int, len(sides))
+		playerYellows := make([][]int, len(sides))
+		bps := make([][]float64, len(sides))
+
+		for s, side := range sides {
+			minutes[s] = sampleLineup(rng, side.models)
+			goalWeights := make([]float64, len(side.models))
+			assistWeights := make([]float64, len(side.models))
+			for i, model := range side.models {
+				goalWeights[i] = model.goalsPer90 * minutes[s][i] / 90
+				assistWeights[i] = model.assistsPer90 * minutes[s][i] / 90
+			}
+
+			playerGoals[s] = make([]int, len(side.models))
+			playerAssists[s] = make([]int, len(side.models))
+			for goal := 0; goal < goals[s]; goal++ {
+				scorer := weightedChoice(rng, goalWeights, -1)
+				if scorer < 0 {
+					continue
+				}
+				playerGoals[s][scorer]++
+				if rng.Float64() < assistedGoalChance {
+					if assister := weightedChoice(rng, assistWeights, scorer); assister >= 0 {
+						playerAssists[s][assister]++
+					}
+				}
+			}
+
+			playerSaves[s] = make([]int, len(side.models))
+			playerYellows[s] = make([]int, len(side.models))
+			bps[s] = make([]float64, len(side.models))
+			for i, model := range side.models {
+				if minutes[s][i] == 0 {
+					continue
+				}
+				if model.rules.savePoints {
+					playerSaves[s][i] = poisson(rng, model.savesPer90*minutes[s][i]/90)
+				}
+				if rng.Float64() < model.yellowsPer90*minutes[s][i]/90 {
+					playerYellows[s][i] = 1
+				}
+				bps[s][i] = model.bps.sample(rng, minutes[s][i]) +
+					eventBPS(model.player.Type.Name, minutes[s][i], playerGoals[s][i], playerAssists[s][i], goals[1-s], playerSaves[s][i], playerYellows[s][i], 0)
+			}
+		}
+
+		bonus := awardBonus(bps, minutes)
+
+		for s, side := range sides {
+			conceded := goals[1-s]
+			for i, model := range side.models {
+				if minutes[s][i] == 0 {
+					continue
+				}
+				points := 1
+				if minutes[s][i] >= 60 {
+					points++
+					if conceded == 0 {
+						points += model.rules.cleanSheetPoints
+					}
+					if model.rules.concededPenalty {
+						points -= conceded / 2
+					}
+				}
+				points += playerGoals[s][i]*model.rules.goalPoints + playerAssists[s][i]*3
+				points += playerSaves[s][i]/3 - playerYellows[s][i] + bonus[s][i]
+				simulation.points[model.player.ID][iteration] = float32(points)
+				simulation.played[model.player.ID][iteration] = true
+				simulation.expectedBonus[model.player.ID] += float32(bonus[s][i]) / float32(iterations)
+			}
+		}
+	}
+
+	cache[cacheKey] = simulation
+
+	return simulation
+}
+
+// ExpectedPoints is the player's mean simulated FPL points for the fixture.
+func (sp StartingPlayer) ExpectedPoints() float32 {
+	simulation := simulateFixture(sp.Fixture, expectedPointsIterations)
+	var total float32
+	points := simulation.points[sp.Player.ID]
+	for _, p := range points {
+		total += p
+	}
+	if len(points) == 0 {
+		return 0
+	}
+	return total / float32(len(points))
+}
+
+type SimulationSummary struct {
+	Iterations   int
+	Mean         float32
+	Median       float32
+	P10          float32
+	P90          float32
+	Target       float32
+	TargetChance float32
+}
+
+// simulateTeam adds up the players' points in each iteration, so players in the same
+// fixture are correlated and players in different fixtures are independent.
+func simulateTeam(players []StartingPlayer, iterations int, target float32) SimulationSummary {
+	totals := make([]float32, iterations)
+	for _, player := range players {
+		points := simulateFixture(player.Fixture, iterations).points[player.Player.ID]
+		for i := range points {
+			totals[i] += points[i]
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i] < totals[j]
+	})
+
+	summary := SimulationSummary{
+		Iterations: iterations,
+		Median:     percentile(totals, 0.5),
+		P10:        percentile(totals, 0.1),
+		P90:        percentile(totals, 0.9),
+		Target:     target,
+	}
+	beaten := 0
+	for _, total := range totals {
+		summary.Mean += total
+		if total > target {
+			beaten++
+		}
+	}
+	if iterations > 0 {
+		summary.Mean /= float32(iterations)
+		summary.TargetChance = float32(beaten) / float32(iterations)
+	}
+
+	return summary
+}
+
+// percentile expects sorted values.
+func percentile(sorted []float32, p float64) float32 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+func poisson(rng *rand.Rand, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	limit := math.Exp(-lambda)
+	count := 0
+	product := rng.Float64()
+	for product > limit {
+		count++
+		product *= rng.Float64()
+	}
+	return count
+}
+
+// weightedChoice picks an index in proportion to its weight, never picking skip.
+// It returns -1 when there's nothing to pick from.
+func weightedChoice(rng *rand.Rand, weights []float64, skip int) int {
+	var total float64
+	for i, weight := range weights {
+		if i != skip {
+			total += weight
+		}
+	}
+	if total <= 0 {
+		return -1
+	}
+	roll := rng.Float64() * total
+	chosen := -1
+	for i, weight := range weights {
+		if i == skip || weight <= 0 {
+			continue
+		}
+		chosen = i
+		roll -= weight
+		if roll < 0 {
+			break
+		}
+	}
+	return chosen
+}
+
+func printSimulation(summary SimulationSummary) {
+	headerFmt, columnFmt := tableFormat()
+	fmt.Printf("\nSimulated points over %d iterations (no captain):\n", summary.Iterations)
+	tbl := table.New("Mean", "Median", "10th %", "90th %", fmt.Sprintf("Chance of beating %.0f", summary.Target))
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	tbl.AddRow(
+		fmt.Sprintf("%.1f", summary.Mean),
+		fmt.Sprintf("%.0f", summary.Median),
+		fmt.Sprintf("%.0f", summary.P10),
+		fmt.Sprintf("%.0f", summary.P90),
+		fmt.Sprintf("%.0f%%", summary.TargetChance*100),
+	)
+	tbl.Print()
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rodaine/table"
+)
+
+const (
+	// gameweeks either side of a swing that are compared
+	swingRun    = 3
+	minSwingRun = 2
+	// how much easier the run after a swing has to be on average to be listed
+	minSwing = 1.0
+	// how many rotation pairs to list for each position
+	rotationPairs = 5
+	// how often a player has to start to be part of a rotation
+	rotationMinStartRate = 0.6
+)
+
+// positions that can be rotated, as the two players share a place in the team
+var rotationPositions = []string{"Goalkeeper", "Defender"}
+
+// FixtureSwing is when a club's run of fixtures turns from hard to easy.
+type FixtureSwing struct {
+	Team *Team
+	// the first gameweek of the easier run
+	Gameweek Gameweek
+	Before   float32
+	After    float32
+}
+
+func (s FixtureSwing) Size() float32 {
+	return s.Before - s.After
+}
+
+// RotationPair is two players from different clubs to play in turns, whoever has the
+// easier fixture each gameweek.
+type RotationPair struct {
+	Players [2]Player
+	// whose club plays each gameweek, 0 or 1
+	Picks []int
+	// average difficulty of the fixtures played, and the better of the two clubs' own
+	Difficulty float32
+	Single     float32
+}
+
+func (p RotationPair) Cost() float32 {
+	return p.Players[0].RawCost + p.Players[1].RawCost
+}
+
+// Gain is how much easier the rotation's fixtures are than either club's alone.
+func (p RotationPair) Gain() float32 {
+	return p.Single - p.Difficulty
+}
+
+// Swings are the fixture swings and rotation pairs found over the ticker's gameweeks.
+type Swings struct {
+	Ticker FixtureTicker
+	Swings []FixtureSwing
+	// keyed by position name
+	Pairs map[string][]RotationPair
+}
+
+// gameweekDifficulty is a club's difficulty for a gameweek, the average of its
+// fixtures or the hardest there is for a blank.
+func gameweekDifficulty(fixtures []TickerFixture) float32 {
+	if len(fixtures) == 0 {
+		return blankDifficulty
+	}
+	var total float32
+	for _, fixture := range fixtures {
+		total += fixture.Difficulty
+	}
+	return total / float32(len(fixtures))
+}
+
+func averageDifficulty(difficulties []float32) float32 {
+	var total float32
+	for _, difficulty := range difficulties {
+		total += difficulty
+	}
+	return total / float32(len(difficulties))
+}
+
+// findSwings scans every club's fixtures over the gameweeks from the given one for
+// runs that turn from hard to easy, comparing the few gameweeks before each one with
+// the few from it, and for goalkeepers and defenders from two clubs whose fixtures
+// take turns being easy. Swings are rated for the view and rotations for defence.
+func (d *Data) findSwings(from GameweekID, weeks int, view string) (Swings, error) {
+	ticker, err := d.fixtureTicker(from, weeks, 0, view)
+	if err != nil {
+		return Swings{}, err
+	}
+	swings := Swings{
+		Ticker: ticker,
+		Pairs:  make(map[string][]RotationPair, 0),
+	}
+
+	for _, row := range ticker.Rows {
+		difficulties := make([]float32, len(row.Fixtures))
+		for week, fixtures := range row.Fixtures {
+			difficulties[week] = gameweekDifficulty(fixtures)
+		}
+
+		var best FixtureSwing
+		for week := minSwingRun; week <= len(difficulties)-minSwingRun; week++ {
+			start, end := week-swingRun, week+swingRun
+			if start < 0 {
+				start = 0
+			}
+			if end > len(difficulties) {
+				end = len(difficulties)
+			}
+			swing := FixtureSwing{
+				Team:     row.Team,
+				Gameweek: ticker.Gameweeks[week],
+				Before:   averageDifficulty(difficulties[start:week]),
+				After:    averageDifficulty(difficulties[week:end]),
+			}
+			if best.Team == nil || swing.Size() > best.Size() {
+				best = swing
+			}
+		}
+		if best.Team != nil && best.Size() >= minSwing {
+			swings.Swings = append(swings.Swings, best)
+		}
+	}
+	sort.SliceStable(swings.Swings, func(i, j int) bool {
+		return swings.Swings[i].Size() > swings.Swings[j].Size()
+	})
+
+	defence := ticker
+	if view != defenceView {
+		if defence, err = d.fixtureTicker(from, weeks, 0, defenceView); err != nil {
+			return Swings{}, err
+		}
+	}
+	for _, position := range rotationPositions {
+		swings.Pairs[position] = rotationPairsFor(defence, position)
+	}
+
+	return swings, nil
+}
+
+// rotationPairsFor pairs up every two clubs, playing whichever has the easier
+// gameweek, and keeps the pairs whose fixtures are easiest together. Each club's
+// cheapest regular starter in the position stands for it.
+func rotationPairsFor(ticker FixtureTicker, position string) []RotationPair {
+	type club struct {
+		player       Player
+		difficulties []float32
+		average      float32
+	}
+	clubs := make([]club, 0, len(ticker.Rows))
+	for _, row := range ticker.Rows {
+		starter, ok := cheapestStarter(row.Team, position)
+		if !ok {
+			continue
+		}
+		c := club{player: starter, difficulties: make([]float32, len(row.Fixtures))}
+		for week, fixtures := range row.Fixtures {
+			c.difficulties[week] = gameweekDifficulty(fixtures)
+		}
+		c.average = averageDifficulty(c.difficulties)
+		clubs = append(clubs, c)
+	}
+
+	pairs := make([]RotationPair, 0)
+	for i := range clubs {
+		for j := i + 1; j < len(clubs); j++ {
+			pair := RotationPair{
+				Players: [2]Player{clubs[i].player, clubs[j].player},
+				Picks:   make([]int, len(ticker.Gameweeks)),
+				Single:  clubs[i].average,
+			}
+			if clubs[j].average < pair.Single {
+				pair.Single = clubs[j].average
+			}
+			played := make([]float32, len(ticker.Gameweeks))
+			for week := range ticker.Gameweeks {
+				played[week] = clubs[i].difficulties[week]
+				if clubs[j].difficulties[week] < played[week] {
+					played[week] = clubs[j].difficulties[week]
+					pair.Picks[week] = 1
+				}
+			}
+			pair.Difficulty = averageDifficulty(played)
+			pairs = append(pairs, pair)
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Difficulty != pairs[j].Difficulty {
+			return pairs[i].Difficulty < pairs[j].Difficulty
+		}
+		return pairs[i].Cost() < pairs[j].Cost()
+	})
+	if len(pairs) > rotationPairs {
+		pairs = pairs[:rotationPairs]
+	}
+	return pairs
+}
+
+// cheapestStarter is the club's cheapest player in the position who usually starts,
+// the one with more points per game if two cost the same.
+func cheapestStarter(team *Team, position string) (Player, bool) {
+	var cheapest Player
+	found := false
+	for _, player := range team.Players {
+		if player.Type.Name != position {
+			continue
+		}
+		var minutes MinutesPrediction
+		if len(player.History) > 0 {
+			minutes = predictMinutesFromHistory(player.History)
+		} else {
+			minutes = predictMinutesFromSeason(player)
+		}
+		if minutes.StartRate < rotationMinStartRate {
+			continue
+		}
+		if !found || player.RawCost < cheapest.RawCost ||
+			(player.RawCost == cheapest.RawCost && player.PointsPerGame > cheapest.PointsPerGame) {
+			cheapest = player
+			found = true
+		}
+	}
+	return cheapest, found
+}
+
+func printSwings(swings Swings) {
+	headerFmt, columnFmt := tableFormat()
+
+	if len(swings.Swings) == 0 {
+		fmt.Printf("\nNo club's fixtures turn from hard to easy over the next %d gameweeks.\n", len(swings.Ticker.Gameweeks))
+	} else {
+		fmt.Printf("\nClubs whose fixtures to %s in turn easier:\n", tickerGoals[swings.Ticker.View])
+		tbl := table.New("Team", "From", "Before", "After", "Swing")
+		tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+		for _, swing := range swings.Swings {
+			tbl.AddRow(
+				swing.Team.ShortName,
+				swing.Gameweek.Name,
+				fmt.Sprintf("%.2f", swing.Before),
+				fmt.Sprintf("%.2f", swing.After),
+				fmt.Sprintf("%.2f", swing.Size()),
+			)
+		}
+		tbl.Print()
+	}
+
+	gameweeks := make([]string, 0, len(swings.Ticker.Gameweeks))
+	for _, gameweek := range swings.Ticker.Gameweeks {
+		gameweeks = append(gameweeks, fmt.Sprintf("GW%d", gameweek.ID))
+	}
+	for _, position := range rotationPositions {
+		pairs := swings.Pairs[position]
+		if len(pairs) == 0 {
+			continue
+		}
+		fmt.Printf("\n%ss to rotate, playing whoever's club is listed for %s:\n", position, strings.Join(gameweeks, ", "))
+		tbl := table.New("Players", "Cost", "Rotation", "Avg", "Gain")
+		tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+		for _, pair := range pairs {
+			picks := make([]string, 0, len(pair.Picks))
+			for _, pick := range pair.Picks {
+				picks = append(picks, pair.Players[pick].Team.ShortName)
+			}
+			tbl.AddRow(
+				fmt.Sprintf("%s (%s) + %s (%s)", pair.Players[0].Name, pair.Players[0].Team.ShortName, pair.Players[1].Name, pair.Players[1].Team.ShortName),
+				fmt.Sprintf("£%.1fm", pair.Cost()),
+				strings.Join(picks, " "),
+				fmt.Sprintf("%.2f", pair.Difficulty),
+				fmt.Sprintf("%.2f", pair.Gain()),
+			)
+		}
+		tbl.Print()
+	}
+}
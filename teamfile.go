@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// how many players an ambiguous name lists
+const ambiguousNameOptions = 5
+
+// teamFile is a squad kept by hand rather than fetched with a manager id.
+type teamFile struct {
+	Players   []string `json:"players"`
+	BankValue float32  `json:"bank_value"`
+}
+
+// LoadTeamFile reads a squad of player names and a bank value, e.g. team.json. The
+// selling prices aren't known, so each player sells for their current price.
+func (d *Data) LoadTeamFile(path string, gameweekID GameweekID) (TeamConfig, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return TeamConfig{}, err
+	}
+
+	var file teamFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		return TeamConfig{}, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	gameweekPlayerSet := d.GameweekPlayerSet(gameweekID)
+	config := TeamConfig{
+		BankValue:     file.BankValue,
+		SellingPrices: make(map[PlayerID]float32, 0),
+	}
+	for _, name := range file.Players {
+		player, err := d.resolvePlayerName(name)
+		if err != nil {
+			return TeamConfig{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if _, ok := config.SellingPrices[player.ID]; ok {
+			return TeamConfig{}, fmt.Errorf("%s: %s is in the squad twice", path, player.Name)
+		}
+		config.Squad = append(config.Squad, player)
+		config.SellingPrices[player.ID] = player.RawCost
+		// for players missing from gameweek i.e. no fixture
+		if startingPlayer, ok := gameweekPlayerSet[player.ID]; ok {
+			config.Players = append(config.Players, startingPlayer)
+		}
+	}
+
+	if err := d.checkSquadShape(config.Squad); err != nil {
+		return TeamConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// checkSquadShape is whether the squad is one FPL allows: the right number of players
+// in each position and no more than three from a club.
+func (d *Data) checkSquadShape(squad []Player) error {
+	size := 0
+	for _, playerType := range d.PlayerTypes {
+		size += playerType.TeamPlayerCount
+	}
+	if len(squad) != size {
+		return fmt.Errorf("the squad needs %d players but has %d", size, len(squad))
+	}
+
+	byType := make(map[PlayerTypeID]int, 0)
+	byClub := make(map[TeamID]int, 0)
+	for _, player := range squad {
+		byType[player.Type.ID]++
+		byClub[player.Team.ID]++
+	}
+	for _, playerType := range d.PlayerTypes {
+		if count := byType[playerType.ID]; count != playerType.TeamPlayerCount {
+			return fmt.Errorf("the squad needs %d %s but has %d", playerType.TeamPlayerCount, strings.ToLower(playerType.PluralName), count)
+		}
+	}
+	for _, team := range d.Teams {
+		if count := byClub[team.ID]; count > maxPlayersPerClub {
+			return fmt.Errorf("the squad has %d players from %s but can only have %d", count, team.Name, maxPlayersPerClub)
+		}
+	}
+	return nil
+}
+
+// resolvePlayerName finds the one player a name means, ignoring accents and case.
+// A name can be followed by the club to tell players apart, e.g. "Cash (AVL)". An
+// exact match beats a match on part of the name, e.g. "Porro" for "Pedro Porro",
+// which beats a fuzzy one, but two matches at the same level are an error.
+func (d *Data) resolvePlayerName(name string) (Player, error) {
+	search, club := name, ""
+	if open := strings.LastIndex(name, "("); open > 0 && strings.HasSuffix(name, ")") {
+		search = strings.TrimSpace(name[:open])
+		club = strings.TrimSpace(name[open+1 : len(name)-1])
+	}
+	flatSearch := strings.ToLower(flattenName(search))
+
+	exact := make([]Player, 0)
+	partial := make([]Player, 0)
+	fuzzyMatches := make([]Player, 0)
+	for _, player := range d.Players {
+		if club != "" && !strings.EqualFold(player.Team.ShortName, club) && !strings.EqualFold(player.Team.Name, club) {
+			continue
+		}
+		flatName := strings.ToLower(flattenName(player.Name))
+		switch {
+		case flatName == flatSearch:
+			exact = append(exact, player)
+		case containsWord(flatName, flatSearch):
+			partial = append(partial, player)
+		case matchesName(player.Name, search):
+			fuzzyMatches = append(fuzzyMatches, player)
+		}
+	}
+
+	for _, matches := range [][]Player{exact, partial, fuzzyMatches} {
+		if len(matches) == 1 {
+			return matches[0], nil
+		}
+		if len(matches) > 1 {
+			options := make([]string, 0, ambiguousNameOptions+1)
+			for i, player := range matches {
+				if i == ambiguousNameOptions {
+					options = append(options, fmt.Sprintf("%d others", len(matches)-i))
+					break
+				}
+				options = append(options, fmt.Sprintf("%s (%s)", player.Name, player.Team.ShortName))
+			}
+			return Player{}, fmt.Errorf("'%s' could be %s, add the club to say which", name, strings.Join(options, " or "))
+		}
+	}
+	return Player{}, fmt.Errorf("player '%s' not found", name)
+}
+
+// containsWord is whether the words of search appear together in name, e.g. "porro"
+// in "pedro porro" or "fernandes" in "b.fernandes" but not "son" in "johnson".
+func containsWord(name string, search string) bool {
+	words := strings.NewReplacer(".", " ", "-", " ")
+	return strings.Contains(" "+words.Replace(name)+" ", " "+words.Replace(search)+" ")
+}
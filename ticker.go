@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+)
+
+const (
+	attackView  = "attack"
+	defenceView = "defence"
+	// a blank counts towards a run like the hardest fixture there is
+	blankDifficulty = 5
+)
+
+// colours for each difficulty, like the ones on the FPL site
+var difficultyColours = []*color.Color{
+	color.New(color.BgGreen, color.FgBlack),
+	color.New(color.BgHiGreen, color.FgBlack),
+	color.New(color.BgWhite, color.FgBlack),
+	color.New(color.BgHiRed, color.FgBlack),
+	color.New(color.BgRed, color.FgWhite),
+}
+
+// what each view rates fixtures for
+var tickerGoals = map[string]string{
+	attackView:  "score",
+	defenceView: "keep a clean sheet",
+}
+
+var colourCodes = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// TickerFixture is one of a team's fixtures in the ticker.
+type TickerFixture struct {
+	Opponent   *Team
+	Home       bool
+	Difficulty float32
+}
+
+// TickerRow is a team's fixtures over the ticker's gameweeks, in the same order.
+type TickerRow struct {
+	Team     *Team
+	Fixtures [][]TickerFixture
+	// over the gameweeks it's sorted by
+	AverageDifficulty float32
+	FixtureCount      int
+	Blanks            int
+	Doubles           int
+}
+
+// FixtureTicker is every club's run of fixtures, easiest run first.
+type FixtureTicker struct {
+	View      string
+	Gameweeks []Gameweek
+	// how many of the gameweeks the rows are sorted by
+	Window int
+	Rows   []TickerRow
+}
+
+// TeamDifficulty is FPL's difficulty of the fixture for the given team, 1 to 5.
+func (f *Fixture) TeamDifficulty(teamID TeamID) int {
+	if f.AwayTeam != nil && teamID == f.AwayTeam.ID {
+		return f.AwayTeamDifficulty
+	}
+	return f.HomeTeamDifficulty
+}
+
+// ViewDifficulty is how hard the fixture is for the team to score in for the attack
+// view or to keep a clean sheet in for the defence view.
+func (f *Fixture) ViewDifficulty(teamID TeamID, view string) float32 {
+	if view == defenceView {
+		return f.DefenceDifficulty(teamID)
+	}
+	return f.AttackDifficulty(teamID)
+}
+
+// fixtureTicker lays out every club's fixtures for the gameweeks from the given one
+// and sorts the clubs by their average difficulty over the first window of them.
+// Each blank counts as a fixture as hard as the hardest, and both of a double's
+// fixtures count, with ties going to the club with more fixtures.
+func (d *Data) fixtureTicker(from GameweekID, weeks int, window int, view string) (FixtureTicker, error) {
+	if view != attackView && view != defenceView {
+		return FixtureTicker{}, fmt.Errorf("-view: expected %s or %s, got '%s'", attackView, defenceView, view)
+	}
+
+	ticker := FixtureTicker{View: view}
+	for _, gameweek := range d.Gameweeks {
+		if gameweek.ID >= from && len(ticker.Gameweeks) < weeks {
+			ticker.Gameweeks = append(ticker.Gameweeks, gameweek)
+		}
+	}
+	if len(ticker.Gameweeks) == 0 {
+		return FixtureTicker{}, fmt.Errorf("there are no gameweeks left to show fixtures for")
+	}
+	ticker.Window = window
+	if ticker.Window <= 0 || ticker.Window > len(ticker.Gameweeks) {
+		ticker.Window = len(ticker.Gameweeks)
+	}
+
+	for _, team := range d.Teams {
+		row := TickerRow{
+			Team:     team,
+			Fixtures: make([][]TickerFixture, len(ticker.Gameweeks)),
+		}
+		for _, fixture := range team.Fixtures {
+			for week, gameweek := range ticker.Gameweeks {
+				if fixture.Gameweek.ID != gameweek.ID {
+					continue
+				}
+				tickerFixture := TickerFixture{
+					Opponent:   fixture.AwayTeam,
+					Home:       fixture.HomeTeam.ID == team.ID,
+					Difficulty: fixture.ViewDifficulty(team.ID, view),
+				}
+				if !tickerFixture.Home {
+					tickerFixture.Opponent = fixture.HomeTeam
+				}
+				row.Fixtures[week] = append(row.Fixtures[week], tickerFixture)
+			}
+		}
+
+		var total float32
+		for _, fixtures := range row.Fixtures[:ticker.Window] {
+			switch {
+			case len(fixtures) == 0:
+				row.Blanks++
+				total += blankDifficulty
+			case len(fixtures) > 1:
+				row.Doubles++
+			}
+			for _, fixture := range fixtures {
+				row.FixtureCount++
+				total += fixture.Difficulty
+			}
+		}
+		row.AverageDifficulty = total / float32(row.FixtureCount+row.Blanks)
+
+		ticker.Rows = append(ticker.Rows, row)
+	}
+
+	sort.SliceStable(ticker.Rows, func(i, j int) bool {
+		if ticker.Rows[i].AverageDifficulty != ticker.Rows[j].AverageDifficulty {
+			return ticker.Rows[i].AverageDifficulty < ticker.Rows[j].AverageDifficulty
+		}
+		return ticker.Rows[i].FixtureCount > ticker.Rows[j].FixtureCount
+	})
+
+	return ticker, nil
+}
+
+func difficultyColour(difficulty float32) *color.Color {
+	i := int(difficulty+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(difficultyColours) {
+		i = len(difficultyColours) - 1
+	}
+	return difficultyColours[i]
+}
+
+// formatDifficulty leaves off the decimal for whole difficulties, like FPL's.
+func formatDifficulty(difficulty float32) string {
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", difficulty), ".0")
+}
+
+// visibleWidth is how wide a string is in the terminal, without its colour codes.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(colourCodes.ReplaceAllString(s, ""))
+}
+
+func printFixtureTicker(ticker FixtureTicker) {
+	headerFmt, columnFmt := tableFormat()
+
+	first, last := ticker.Gameweeks[0], ticker.Gameweeks[ticker.Window-1]
+	over := first.Name
+	if first.ID != last.ID {
+		over = fmt.Sprintf("%s to %s", first.Name, last.Name)
+	}
+	ratings := "FPL's"
+	if scoringConfig.Ratings != nil {
+		ratings = "custom"
+	}
+	fmt.Printf("\nThe easiest fixtures to %s in over %s, by %s ratings, are:\n", tickerGoals[ticker.View], over, ratings)
+
+	headers := []interface{}{"Team"}
+	for _, gameweek := range ticker.Gameweeks {
+		headers = append(headers, fmt.Sprintf("GW%d", gameweek.ID))
+	}
+	headers = append(headers, "Avg", "Fixtures")
+	tbl := table.New(headers...)
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt).WithWidthFunc(visibleWidth)
+
+	for _, row := range ticker.Rows {
+		cells := []interface{}{row.Team.ShortName}
+		for _, fixtures := range row.Fixtures {
+			if len(fixtures) == 0 {
+				cells = append(cells, "BLANK")
+				continue
+			}
+			parts := make([]string, 0, len(fixtures))
+			for _, fixture := range fixtures {
+				venue := "A"
+				if fixture.Home {
+					venue = "H"
+				}
+				text := fmt.Sprintf(" %s (%s) %s ", fixture.Opponent.ShortName, venue, formatDifficulty(fixture.Difficulty))
+				parts = append(parts, difficultyColour(fixture.Difficulty).Sprint(text))
+			}
+			cells = append(cells, strings.Join(parts, "+"))
+		}
+		cells = append(cells, fmt.Sprintf("%.2f", row.AverageDifficulty), tickerFixtureCount(row))
+		tbl.AddRow(cells...)
+	}
+	tbl.Print()
+}
+
+// tickerFixtureCount is the number of fixtures with any blanks and doubles marked.
+func tickerFixtureCount(row TickerRow) string {
+	notes := make([]string, 0)
+	if row.Doubles > 0 {
+		notes = append(notes, fmt.Sprintf("%d DGW", row.Doubles))
+	}
+	if row.Blanks > 0 {
+		notes = append(notes, fmt.Sprintf("%d BGW", row.Blanks))
+	}
+	if len(notes) == 0 {
+		return fmt.Sprintf("%d", row.FixtureCount)
+	}
+	return fmt.Sprintf("%d (%s)", row.FixtureCount, strings.Join(notes, ", "))
+}
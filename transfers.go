@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rodaine/table"
+)
+
+// TransferOption is a set of players to sell and buy together this gameweek.
+type TransferOption struct {
+	Out []Player
+	In  []Player
+	// left in the bank afterwards
+	Bank float32
+	// expected points the squad gains this gameweek
+	Gain float32
+	// points deducted for transfers beyond the free ones
+	Hit int
+}
+
+func (o TransferOption) GainPerTransfer() float32 {
+	return o.Gain / float32(len(o.In))
+}
+
+// NetGain is the gain after the hit.
+func (o TransferOption) NetGain() float32 {
+	return o.Gain - float32(o.Hit)
+}
+
+// transferSearch finds the best ways to sell k squad players and buy k replacements
+// in the same positions, for every k up to the limit.
+type transferSearch struct {
+	planner       *transferPlanner
+	freeTransfers int
+	// how many options to keep
+	options int
+	// players worth buying in each position, most expected points first
+	buyable map[PlayerTypeID][]Player
+	types   []PlayerTypeID
+	best    []TransferOption
+	// locked players the squad doesn't have, who every option has to buy
+	mustBuy []Player
+
+	// the sale being tried
+	sold    []Player
+	need    map[PlayerTypeID]int
+	current float32
+	hit     int
+}
+
+// searchTransfers lists the best transfers for the gameweek by expected points gained
+// after hits, trying every set of up to maxTransfers sales. Locked players are never
+// sold, and every option buys the ones the squad doesn't have, while excluded players
+// are never bought. A branch stops once even the best players left in each position
+// couldn't beat the options already found.
+func (d *Data) searchTransfers(squad []Player, bank float32, freeTransfers int, gameweekID GameweekID, maxTransfers int, options int, constraints TeamConstraints) []TransferOption {
+	planner := d.newTransferPlanner(gameweekID, 1, constraints)
+	if len(planner.gameweeks) == 0 || options <= 0 {
+		return nil
+	}
+	search := transferSearch{
+		planner:       planner,
+		freeTransfers: freeTransfers,
+		options:       options,
+		buyable:       make(map[PlayerTypeID][]Player, 0),
+		current:       planner.squadPoints(squad, 0),
+		mustBuy:       constraints.lockedOutside(squad),
+	}
+	for _, playerType := range d.PlayerTypes {
+		search.types = append(search.types, playerType.ID)
+	}
+	search.findBuyable(d.Players, squad, maxTransfers)
+
+	sellable := make([]Player, 0, len(squad))
+	for _, player := range squad {
+		if _, ok := constraints.Locked[player.ID]; !ok {
+			sellable = append(sellable, player)
+		}
+	}
+
+	fewest := len(search.mustBuy)
+	if fewest < 1 {
+		fewest = 1
+	}
+	for k := fewest; k <= maxTransfers && k <= len(sellable); k++ {
+		search.hit = 0
+		if k > freeTransfers {
+			search.hit = (k - freeTransfers) * transferHitCost
+		}
+		var sell func(from int, sold []Player)
+		sell = func(from int, sold []Player) {
+			if len(sold) == k {
+				search.trySale(squad, sold, bank)
+				return
+			}
+			for i := from; i < len(sellable); i++ {
+				sell(i+1, append(sold, sellable[i]))
+			}
+		}
+		sell(0, make([]Player, 0, k))
+	}
+
+	return search.best
+}
+
+// findBuyable leaves out anyone with enough players at least as good and no more
+// expensive that one could always be bought instead, even if the others are bought
+// too and the room in their clubs is taken by the rest of the transfers.
+func (s *transferSearch) findBuyable(players []Player, squad []Player, maxTransfers int) {
+	owned := make(map[PlayerID]bool, len(squad))
+	clubCounts := make(map[TeamID]int, 0)
+	for _, player := range squad {
+		owned[player.ID] = true
+		clubCounts[player.Team.ID]++
+	}
+
+	expected := s.planner.expected[0]
+	byType := make(map[PlayerTypeID][]Player, 0)
+	for _, player := range players {
+		if _, ok := s.planner.constraints.Excluded[player.ID]; ok || owned[player.ID] {
+			continue
+		}
+		if _, locked := s.planner.constraints.Locked[player.ID]; locked || expected[player.ID] > 0 {
+			byType[player.Type.ID] = append(byType[player.Type.ID], player)
+		}
+	}
+
+	for typeID, candidates := range byType {
+		sort.Slice(candidates, func(i, j int) bool {
+			if expected[candidates[i].ID] != expected[candidates[j].ID] {
+				return expected[candidates[i].ID] > expected[candidates[j].ID]
+			}
+			if candidates[i].RawCost != candidates[j].RawCost {
+				return candidates[i].RawCost < candidates[j].RawCost
+			}
+			return candidates[i].ID < candidates[j].ID
+		})
+		for i, candidate := range candidates {
+			dominators := 0
+			for _, other := range candidates[:i] {
+				room := s.planner.constraints.clubCap(other.Team.ID) - clubCounts[other.Team.ID]
+				if other.RawCost <= candidate.RawCost && room >= maxTransfers {
+					dominators++
+				}
+			}
+			_, locked := s.planner.constraints.Locked[candidate.ID]
+			if locked || dominators < maxTransfers {
+				s.buyable[typeID] = append(s.buyable[typeID], candidate)
+			}
+		}
+	}
+}
+
+// trySale looks for the best replacements for the players sold.
+func (s *transferSearch) trySale(squad []Player, sold []Player, bank float32) {
+	s.sold = append([]Player{}, sold...)
+	s.need = make(map[PlayerTypeID]int, 0)
+
+	soldIDs := make(map[PlayerID]bool, len(sold))
+	for _, player := range sold {
+		soldIDs[player.ID] = true
+		s.need[player.Type.ID]++
+		bank += player.RawCost
+	}
+	// the locked players have to replace someone in their position
+	for typeID, count := range s.countTypes(s.mustBuy) {
+		if s.need[typeID] < count {
+			return
+		}
+	}
+
+	kept := make([]Player, 0, len(squad))
+	clubCounts := make(map[TeamID]int, 0)
+	for _, player := range squad {
+		if !soldIDs[player.ID] {
+			kept = append(kept, player)
+			clubCounts[player.Team.ID]++
+		}
+	}
+
+	s.buy(kept, make([]Player, 0, len(sold)), roundMoney(bank), clubCounts, 0, 0)
+}
+
+// buy picks the replacements a position at a time, each position's in the order
+// they're listed so every combination is only tried once.
+func (s *transferSearch) buy(kept []Player, bought []Player, bank float32, clubCounts map[TeamID]int, t int, from int) {
+	if t < len(s.types) && s.need[s.types[t]] == s.countType(bought, s.types[t]) {
+		s.buy(kept, bought, bank, clubCounts, t+1, 0)
+		return
+	}
+	if t == len(s.types) {
+		if !buysAll(bought, s.mustBuy) {
+			return
+		}
+		squad := append(append([]Player{}, kept...), bought...)
+		s.add(TransferOption{
+			Out:  s.sold,
+			In:   append([]Player{}, bought...),
+			Bank: bank,
+			Gain: s.planner.squadPoints(squad, 0) - s.current,
+			Hit:  s.hit,
+		})
+		return
+	}
+
+	if s.bound(kept, bought, t, from) <= s.threshold() {
+		return
+	}
+
+	typeID := s.types[t]
+	candidates := s.buyable[typeID]
+	for i := from; i < len(candidates); i++ {
+		candidate := candidates[i]
+		if candidate.RawCost > bank || clubCounts[candidate.Team.ID] >= s.planner.constraints.clubCap(candidate.Team.ID) {
+			continue
+		}
+		clubCounts[candidate.Team.ID]++
+		s.buy(kept, append(bought, candidate), roundMoney(bank-candidate.RawCost), clubCounts, t, i+1)
+		clubCounts[candidate.Team.ID]--
+
+		// candidates further down are no better, so check whether any are still worth trying
+		if s.bound(kept, bought, t, i+1) <= s.threshold() {
+			return
+		}
+	}
+}
+
+// bound is the most the squad could gain if every position still to be filled got
+// the best player left in it, ignoring the budget and clubs.
+func (s *transferSearch) bound(kept []Player, bought []Player, t int, from int) float32 {
+	expected := s.planner.expected[0]
+	byType := make(map[PlayerTypeID][]float32, 0)
+	for _, players := range [][]Player{kept, bought} {
+		for _, player := range players {
+			byType[player.Type.ID] = append(byType[player.Type.ID], expected[player.ID])
+		}
+	}
+	for u := t; u < len(s.types); u++ {
+		typeID := s.types[u]
+		start := 0
+		if u == t {
+			start = from
+		}
+		missing := s.need[typeID] - s.countType(bought, typeID)
+		if missing > 0 && start >= len(s.buyable[typeID]) {
+			return s.threshold()
+		}
+		for m := 0; m < missing; m++ {
+			byType[typeID] = append(byType[typeID], expected[s.buyable[typeID][start].ID])
+		}
+	}
+	for _, points := range byType {
+		sort.Slice(points, func(i, j int) bool {
+			return points[i] > points[j]
+		})
+	}
+	return s.planner.lineupPoints(byType) - s.current
+}
+
+// threshold is the gain, before this sale's hit, an option needs to make the list.
+func (s *transferSearch) threshold() float32 {
+	if len(s.best) < s.options {
+		return float32(-s.hit) - 1e6
+	}
+	return s.best[len(s.best)-1].NetGain() + float32(s.hit)
+}
+
+func (s *transferSearch) add(option TransferOption) {
+	if len(s.best) == s.options && option.NetGain() <= s.best[len(s.best)-1].NetGain() {
+		return
+	}
+	i := sort.Search(len(s.best), func(i int) bool {
+		return s.best[i].NetGain() < option.NetGain()
+	})
+	s.best = append(s.best, TransferOption{})
+	copy(s.best[i+1:], s.best[i:])
+	s.best[i] = option
+	if len(s.best) > s.options {
+		s.best = s.best[:s.options]
+	}
+}
+
+func (s *transferSearch) countTypes(players []Player) map[PlayerTypeID]int {
+	counts := make(map[PlayerTypeID]int, 0)
+	for _, player := range players {
+		counts[player.Type.ID]++
+	}
+	return counts
+}
+
+func (s *transferSearch) countType(players []Player, typeID PlayerTypeID) int {
+	count := 0
+	for _, player := range players {
+		if player.Type.ID == typeID {
+			count++
+		}
+	}
+	return count
+}
+
+func printTransferOptions(gameweek *Gameweek, options []TransferOption) {
+	headerFmt, columnFmt := tableFormat()
+
+	fmt.Printf("\nThe best transfers for %s are:\n", gameweek.Name)
+	tbl := table.New("Out", "In", "Bank", "Gain", "Per Transfer", "Hit", "Net")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+	for _, option := range options {
+		hit := "-"
+		if option.Hit > 0 {
+			hit = fmt.Sprintf("-%d", option.Hit)
+		}
+		tbl.AddRow(
+			playerNames(option.Out),
+			playerNames(option.In),
+			fmt.Sprintf("£%.1fm", option.Bank),
+			fmt.Sprintf("%+.1f", option.Gain),
+			fmt.Sprintf("%+.1f", option.GainPerTransfer()),
+			hit,
+			fmt.Sprintf("%+.1f", option.NetGain()),
+		)
+	}
+	tbl.Print()
+}